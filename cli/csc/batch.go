@@ -0,0 +1,213 @@
+package csc
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/taskie/csc/models"
+	"github.com/volatiletech/sqlboiler/boil"
+)
+
+const (
+	scanObjectColumns = "id, path, size, mtime, mtime_ns, sha256, status"
+	// scanSelectTopSQL selects the objects directly under the base path.
+	scanSelectTopSQL = "SELECT " + scanObjectColumns + " FROM objects WHERE management_id = ? AND instr(path, '/') = 0"
+	// scanSelectDirSQL selects the objects directly under a directory. The
+	// range on path lets SQLite use the (management_id, path) index.
+	scanSelectDirSQL = "SELECT " + scanObjectColumns +
+		" FROM objects WHERE management_id = ? AND path >= ? AND path < ? AND instr(substr(path, ?), '/') = 0"
+	scanSelectOneSQL = "SELECT " + scanObjectColumns + " FROM objects WHERE management_id = ? AND path = ?"
+	scanInsertSQL    = "INSERT INTO objects (management_id, path, type, size, mtime, mtime_ns, sha256, status, created_at, updated_at)" +
+		" VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	scanUpdateSQL = "UPDATE objects SET type = ?, size = ?, mtime = ?, mtime_ns = ?, sha256 = ?, status = ?, updated_at = ?" +
+		" WHERE id = ?"
+	// scanUpdateMetaSQL updates the metadata of an object whose content is
+	// unchanged.
+	scanUpdateMetaSQL   = "UPDATE objects SET size = ?, mtime = ?, mtime_ns = ?, updated_at = ? WHERE id = ?"
+	scanUpdateStatusSQL = "UPDATE objects SET status = ?, updated_at = ? WHERE id = ?"
+	scanDeleteSQL       = "DELETE FROM objects WHERE id = ?"
+	// scanLogDeletionSQL records a deleted object for incremental syncs.
+	scanLogDeletionSQL = "INSERT INTO deletions (scan_run_id, management_id, path, deleted_at) VALUES (?, ?, ?, ?)"
+)
+
+var scanStatements = []string{
+	scanSelectTopSQL,
+	scanSelectDirSQL,
+	scanSelectOneSQL,
+	scanInsertSQL,
+	scanUpdateSQL,
+	scanUpdateMetaSQL,
+	scanUpdateStatusSQL,
+	scanDeleteSQL,
+	scanLogDeletionSQL,
+}
+
+// scanBatch groups the writes of a scan into transactions of up to size
+// statements, which saves SQLite an fsync per file. The statements are
+// prepared once and bound to each transaction.
+type scanBatch struct {
+	ctx     context.Context
+	db      *sql.DB
+	size    int
+	stmts   map[string]*sql.Stmt
+	tx      *sql.Tx
+	txStmts map[string]*sql.Stmt
+	pending int
+}
+
+func newScanBatch(ctx context.Context, db *sql.DB, size int) (*scanBatch, error) {
+	if size < 1 {
+		size = 1
+	}
+	b := &scanBatch{ctx: ctx, db: db, size: size, stmts: make(map[string]*sql.Stmt)}
+	for _, query := range scanStatements {
+		stmt, err := db.PrepareContext(ctx, query)
+		if err != nil {
+			b.close()
+			return nil, err
+		}
+		b.stmts[query] = stmt
+	}
+	return b, nil
+}
+
+func (b *scanBatch) begin() error {
+	if b.tx != nil {
+		return nil
+	}
+	tx, err := b.db.BeginTx(b.ctx, nil)
+	if err != nil {
+		return err
+	}
+	b.tx = tx
+	b.txStmts = make(map[string]*sql.Stmt)
+	return nil
+}
+
+func (b *scanBatch) stmt(query string) (*sql.Stmt, error) {
+	err := b.begin()
+	if err != nil {
+		return nil, err
+	}
+	stmt, ok := b.txStmts[query]
+	if !ok {
+		stmt = b.tx.StmtContext(b.ctx, b.stmts[query])
+		b.txStmts[query] = stmt
+	}
+	return stmt, nil
+}
+
+// exec runs a prepared write in the current transaction and commits it
+// once it is full.
+func (b *scanBatch) exec(query string, args ...interface{}) error {
+	stmt, err := b.stmt(query)
+	if err != nil {
+		return err
+	}
+	_, err = stmt.ExecContext(b.ctx, args...)
+	if err != nil {
+		return err
+	}
+	return b.written()
+}
+
+// executor returns the current transaction for writes which are not
+// prepared. Call written after each of them.
+func (b *scanBatch) executor() (boil.ContextExecutor, error) {
+	err := b.begin()
+	if err != nil {
+		return nil, err
+	}
+	return b.tx, nil
+}
+
+func (b *scanBatch) written() error {
+	b.pending++
+	if b.pending < b.size {
+		return nil
+	}
+	return b.commit()
+}
+
+func (b *scanBatch) commit() error {
+	if b.tx == nil {
+		return nil
+	}
+	tx := b.tx
+	b.tx, b.txStmts, b.pending = nil, nil, 0
+	return tx.Commit()
+}
+
+func (b *scanBatch) close() {
+	if b.tx != nil {
+		b.tx.Rollback()
+		b.tx = nil
+	}
+	for _, stmt := range b.stmts {
+		stmt.Close()
+	}
+}
+
+func scanObjectRows(rows *sql.Rows) ([]*models.Object, error) {
+	defer rows.Close()
+	fs := make([]*models.Object, 0)
+	for rows.Next() {
+		f := &models.Object{}
+		err := rows.Scan(&f.ID, &f.Path, &f.Size, &f.Mtime, &f.MtimeNs, &f.Sha256, &f.Status)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+	return fs, rows.Err()
+}
+
+// selectDir returns the objects directly under the directory dir.
+func (b *scanBatch) selectDir(managementID int64, dir string) ([]*models.Object, error) {
+	var rows *sql.Rows
+	if dir == "." {
+		stmt, err := b.stmt(scanSelectTopSQL)
+		if err != nil {
+			return nil, err
+		}
+		rows, err = stmt.QueryContext(b.ctx, managementID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		prefix := dir
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		// every path starting with prefix is less than this since '0'
+		// follows '/'
+		limit := prefix[:len(prefix)-1] + "0"
+		stmt, err := b.stmt(scanSelectDirSQL)
+		if err != nil {
+			return nil, err
+		}
+		rows, err = stmt.QueryContext(b.ctx, managementID, prefix, limit, utf8.RuneCountInString(prefix)+1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return scanObjectRows(rows)
+}
+
+func (b *scanBatch) selectOne(managementID int64, path string) (*models.Object, error) {
+	stmt, err := b.stmt(scanSelectOneSQL)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(b.ctx, managementID, path)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := scanObjectRows(rows)
+	if err != nil || len(fs) == 0 {
+		return nil, err
+	}
+	return fs[0], nil
+}
@@ -0,0 +1,173 @@
+package csc
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/taskie/csc/models"
+)
+
+func countTestObjects(t *testing.T, b *scanBatch) int64 {
+	n, err := models.Objects().Count(context.Background(), b.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestScanBatchCommitsWhenFull(t *testing.T) {
+	db := openTestDB(t, nil)
+	b, err := newScanBatch(context.Background(), db, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.close()
+	now := time.Now()
+	insert := func(path string) {
+		err := b.exec(scanInsertSQL, 0, path, "b", 1, now, now.UnixNano(), "sha256-of-"+path, "ok", now, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	insert("a")
+	insert("b")
+	if n := countTestObjects(t, b); n != 0 {
+		t.Errorf("%d objects are visible before the batch is full", n)
+	}
+	// writes which are not prepared count as well
+	exec, err := b.executor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = exec.ExecContext(b.ctx, scanInsertSQL, 0, "c", "b", 1, now, now.UnixNano(), "sha256-of-c", "ok", now, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.written()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := countTestObjects(t, b); n != 3 {
+		t.Errorf("%d objects are visible after the batch is full, want 3", n)
+	}
+
+	insert("d")
+	err = b.commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := countTestObjects(t, b); n != 4 {
+		t.Errorf("%d objects are visible after a commit, want 4", n)
+	}
+
+	// close rolls back what is not committed
+	insert("e")
+	b.close()
+	if n := countTestObjects(t, b); n != 4 {
+		t.Errorf("%d objects are visible after close, want 4", n)
+	}
+}
+
+func TestScanBatchSelectDir(t *testing.T) {
+	db := openTestDB(t, map[string]int64{
+		"x": 1, "y": 1, "a/x": 1, "a/y": 1, "a/b/x": 1, "a-b/x": 1, "a.b": 1, "a0/x": 1, "ab/x": 1,
+	})
+	b, err := newScanBatch(context.Background(), db, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.close()
+	cases := []struct {
+		dir  string
+		want []string
+	}{
+		{".", []string{"a.b", "x", "y"}},
+		{"a", []string{"a/x", "a/y"}},
+		{"a/", []string{"a/x", "a/y"}},
+		{"a/b", []string{"a/b/x"}},
+		{"a-b", []string{"a-b/x"}},
+		{"c", []string{}},
+	}
+	for _, c := range cases {
+		fs, err := b.selectDir(0, c.dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]string, len(fs))
+		for i, f := range fs {
+			got[i] = f.Path
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("selectDir(%q) = %q, want %q", c.dir, got, c.want)
+		}
+	}
+	f, err := b.selectOne(0, "a/b/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f == nil || f.Path != "a/b/x" {
+		t.Errorf("selectOne(a/b/x) = %+v", f)
+	}
+	f, err = b.selectOne(0, "a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != nil {
+		t.Errorf("selectOne(a/b) = %+v, want nil", f)
+	}
+}
+
+func TestScannerLookupUsesLoadedDirs(t *testing.T) {
+	db := openTestDB(t, map[string]int64{"a/x": 1, "a/b/y": 1, "c/z": 1})
+	s := newTestScanner(t, db)
+	target := &scanTarget{}
+	for _, dir := range []string{".", "a", "a/b"} {
+		err := s.loadDir(target, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// rows changed behind the loaded directories are not seen
+	err := s.batch.commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec("DELETE FROM objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"a/x", "a/b/y"} {
+		f, err := s.lookup(target, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f == nil || f.Path != path {
+			t.Errorf("lookup(%s) = %+v", path, f)
+		}
+	}
+	f, err := s.lookup(target, "c/z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != nil {
+		t.Errorf("lookup(c/z) = %+v, want nil from the DB", f)
+	}
+
+	// leaving a/b for c drops a/b and a
+	err = s.loadDir(target, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make([]string, len(s.dirs))
+	for i, d := range s.dirs {
+		paths[i] = d.path
+	}
+	if want := []string{".", "c"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("loaded dirs: %q, want %q", paths, want)
+	}
+}
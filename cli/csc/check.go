@@ -0,0 +1,256 @@
+package csc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/taskie/csc"
+	"github.com/taskie/csc/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+const (
+	CheckOK      = "OK"
+	CheckFailed  = "FAILED"
+	CheckMissing = "MISSING"
+	CheckExtra   = "EXTRA"
+	CheckMoved   = "MOVED"
+)
+
+type checkResult struct {
+	Status string
+	Path   string
+	// MovedFrom is the expected path of a MOVED file.
+	MovedFrom string
+}
+
+func loadExpectedFromManifest(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := csc.ParseManifest(f)
+	if err != nil {
+		return nil, err
+	}
+	expected := make(map[string]string, len(entries))
+	for _, e := range entries {
+		expected[filepath.ToSlash(filepath.Clean(e.Path))] = e.Sha256
+	}
+	return expected, nil
+}
+
+// loadExpectedFromDB loads the checksums of the objects of a root in the
+// csc.db at path, keyed by paths relative to dir. dir stands for the root
+// named rootName, or for the directory containing csc.db if rootName is
+// empty. Absolute paths stored in abs mode are made relative to dir, and
+// those outside of it are left out.
+func loadExpectedFromDB(ctx context.Context, path string, rootName string, dir string) (map[string]string, error) {
+	_, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	var managementID int64
+	if rootName != "" {
+		m, err := findManagement(ctx, db, rootName)
+		if err != nil {
+			return nil, err
+		}
+		managementID = m.ID.Int64
+	}
+	fs, err := models.Objects(
+		qm.Where(models.ObjectColumns.ManagementID+" = ?", managementID),
+		qm.And(models.ObjectColumns.Sha256+" <> ''")).All(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	expected := make(map[string]string, len(fs))
+	for _, f := range fs {
+		p := f.Path
+		if filepath.IsAbs(filepath.FromSlash(p)) {
+			rel, err := filepath.Rel(absDir, filepath.FromSlash(p))
+			if err != nil {
+				return nil, err
+			}
+			p = filepath.ToSlash(rel)
+			if p == ".." || strings.HasPrefix(p, "../") {
+				continue
+			}
+		}
+		expected[p] = f.Sha256
+	}
+	return expected, nil
+}
+
+// checkTree hashes the regular files under dir and compares them to the
+// expected checksums keyed by slash-separated paths relative to dir.
+func checkTree(dir string, expected map[string]string) ([]*checkResult, error) {
+	actual := make(map[string]string)
+	results := make([]*checkResult, 0)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			logrus.Error(err)
+			return nil
+		}
+		if !info.Mode().IsRegular() || isDBFile(filepath.Base(path)) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		expectedSha256, ok := expected[rel]
+		if !ok && !checkExtra {
+			return nil
+		}
+		sha256Hex, err := csc.CalcSha256HexString(path)
+		if err != nil {
+			logrus.Error(err)
+			if ok {
+				results = append(results, &checkResult{Status: CheckFailed, Path: rel})
+				actual[rel] = ""
+			}
+			return nil
+		}
+		actual[rel] = sha256Hex
+		if !ok {
+			return nil
+		}
+		if sha256Hex == expectedSha256 {
+			results = append(results, &checkResult{Status: CheckOK, Path: rel})
+		} else {
+			results = append(results, &checkResult{Status: CheckFailed, Path: rel})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Files whose content is expected elsewhere but which are not expected
+	// at their own path are candidates for moved files.
+	extraBySha256 := make(map[string][]string)
+	for path, sha256Hex := range actual {
+		if _, ok := expected[path]; !ok {
+			extraBySha256[sha256Hex] = append(extraBySha256[sha256Hex], path)
+		}
+	}
+	for _, paths := range extraBySha256 {
+		sort.Strings(paths)
+	}
+	missings := make([]string, 0)
+	for path := range expected {
+		if _, ok := actual[path]; !ok {
+			missings = append(missings, path)
+		}
+	}
+	sort.Strings(missings)
+	for _, path := range missings {
+		sha256Hex := expected[path]
+		if candidates := extraBySha256[sha256Hex]; len(candidates) > 0 {
+			results = append(results, &checkResult{Status: CheckMoved, Path: candidates[0], MovedFrom: path})
+			extraBySha256[sha256Hex] = candidates[1:]
+			continue
+		}
+		results = append(results, &checkResult{Status: CheckMissing, Path: path})
+	}
+	for _, paths := range extraBySha256 {
+		for _, path := range paths {
+			results = append(results, &checkResult{Status: CheckExtra, Path: path})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+	return results, nil
+}
+
+var (
+	checkManifest  string
+	checkAgainstDB string
+	checkExtra     bool
+	checkQuiet     bool
+)
+
+func check(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	var expected map[string]string
+	var err error
+	switch {
+	case checkManifest != "" && checkAgainstDB != "":
+		logrus.Fatal("--manifest and --against-db are exclusive")
+	case checkManifest != "" && rootName != "":
+		logrus.Fatal("--root needs --against-db")
+	case checkManifest != "":
+		expected, err = loadExpectedFromManifest(checkManifest)
+	case checkAgainstDB != "":
+		expected, err = loadExpectedFromDB(ctx, checkAgainstDB, rootName, dir)
+	default:
+		logrus.Fatal("either --manifest or --against-db is required")
+	}
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	results, err := checkTree(dir, expected)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.Status]++
+		if checkQuiet && r.Status == CheckOK {
+			continue
+		}
+		if r.Status == CheckMoved {
+			fmt.Printf("%s: %s (from %s)\n", r.Path, r.Status, r.MovedFrom)
+		} else {
+			fmt.Printf("%s: %s\n", r.Path, r.Status)
+		}
+	}
+	for _, status := range []string{CheckFailed, CheckMissing, CheckMoved, CheckExtra} {
+		if counts[status] > 0 {
+			fmt.Fprintf(os.Stderr, "%s: WARNING: %d %s\n", CommandName, counts[status], status)
+		}
+	}
+	if counts[CheckFailed]+counts[CheckMissing]+counts[CheckMoved]+counts[CheckExtra] > 0 {
+		os.Exit(1)
+	}
+}
+
+const CheckCommandName = "check"
+
+var CheckCommand = &cobra.Command{
+	Use:  CheckCommandName + " [DIR]",
+	Args: cobra.MaximumNArgs(1),
+	Run:  check,
+}
+
+func init() {
+	CheckCommand.Flags().StringVarP(&checkManifest, "manifest", "m", "", "checksum manifest to verify against")
+	CheckCommand.Flags().StringVar(&checkAgainstDB, "against-db", "", "csc.db to verify against")
+	addRootFlag(CheckCommand)
+	CheckCommand.Flags().BoolVarP(&checkExtra, "extra", "e", false, "hash unexpected files to report EXTRA and MOVED")
+	CheckCommand.Flags().BoolVarP(&checkQuiet, "quiet", "q", false, "don't print OK for each successfully verified file")
+}
@@ -0,0 +1,127 @@
+package csc
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taskie/csc"
+)
+
+func testSha256(t *testing.T, content string) string {
+	s, err := csc.CalcSha256HexStringFromReader(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCheckTree(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a": "a", "b": "b", "c": "c", "e": "e", DBFileName: "db"} {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := map[string]string{
+		"a": testSha256(t, "a"),
+		"b": testSha256(t, "x"),
+		"d": testSha256(t, "c"),
+		"m": testSha256(t, "m"),
+	}
+	cases := []struct {
+		extra bool
+		want  []checkResult
+	}{
+		{false, []checkResult{
+			{Status: CheckOK, Path: "a"},
+			{Status: CheckFailed, Path: "b"},
+			{Status: CheckMissing, Path: "d"},
+			{Status: CheckMissing, Path: "m"},
+		}},
+		{true, []checkResult{
+			{Status: CheckOK, Path: "a"},
+			{Status: CheckFailed, Path: "b"},
+			{Status: CheckMoved, Path: "c", MovedFrom: "d"},
+			{Status: CheckExtra, Path: "e"},
+			{Status: CheckMissing, Path: "m"},
+		}},
+	}
+	defer func() { checkExtra = false }()
+	for _, c := range cases {
+		checkExtra = c.extra
+		results, err := checkTree(dir, expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]checkResult, len(results))
+		for i, r := range results {
+			got[i] = *r
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("checkTree with extra=%v = %+v, want %+v", c.extra, got, c.want)
+		}
+	}
+}
+
+func TestLoadExpectedFromDB(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), DBFileName)
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = csc.MigrateCscDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := addManagement(ctx, db, "photos", "/srv/photos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	now := time.Now()
+	for _, o := range []struct {
+		managementID int64
+		path         string
+		sha256       string
+	}{
+		{0, "a/x", "1"},
+		{0, filepath.ToSlash(filepath.Join(dir, "y")), "2"},
+		{0, "/elsewhere/z", "3"},
+		{0, "unhashed", ""},
+		{m.ID.Int64, "p", "4"},
+	} {
+		_, err = db.ExecContext(ctx, scanInsertSQL, o.managementID, o.path, "b", 1, now, now.UnixNano(), o.sha256, "ok", now, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := []struct {
+		root string
+		want map[string]string
+	}{
+		{"", map[string]string{"a/x": "1", "y": "2"}},
+		{"photos", map[string]string{"p": "4"}},
+	}
+	for _, c := range cases {
+		got, err := loadExpectedFromDB(ctx, path, c.root, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("loadExpectedFromDB with root %q = %v, want %v", c.root, got, c.want)
+		}
+	}
+	_, err = loadExpectedFromDB(ctx, path, "music", dir)
+	if err == nil {
+		t.Error("loaded the objects of an unknown root")
+	}
+}
@@ -5,11 +5,15 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"time"
+	"strings"
+	"syscall"
+	"unicode/utf8"
 
 	"github.com/iancoleman/strcase"
 	"github.com/k0kubun/pp"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -23,152 +27,181 @@ import (
 type Config struct {
 	LogLevel string
 	AbsMode  bool
+	DB       string
 }
 
 var configFile string
 var config Config
+
+// dbRoot is the directory containing the opened csc.db. Relative paths in
+// the catalog are relative to it.
+var dbRoot string
 var (
 	verbose, debug, version bool
 )
 
-func buildWalkFunc(ctx context.Context, db *sql.DB, basePath string) func(path string, info os.FileInfo, err error) error {
-	return func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() {
-			return nil
-		}
+// insertObject inserts f. sqlboiler fails to read back the nullable INTEGER
+// PRIMARY KEY of SQLite after the row is inserted, so that error is ignored.
+func insertObject(ctx context.Context, exec boil.ContextExecutor, f *models.Object) error {
+	err := f.Insert(ctx, exec, boil.Infer())
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+const DBFileName = "csc.db"
+
+// isDBFile reports whether name is csc.db or one of the files SQLite keeps
+// beside it, which are not scanned.
+func isDBFile(name string) bool {
+	switch name {
+	case DBFileName, DBFileName + "-wal", DBFileName + "-shm", DBFileName + "-journal":
+		return true
+	}
+	return false
+}
+
+// findDBPath returns the DB configured by --db or the "db" key. Otherwise it
+// searches the current directory and its parents for the nearest csc.db the
+// way git finds .git. If create is set, a missing DB is to be created in the
+// current directory, and otherwise it is an error.
+func findDBPath(create bool) (string, error) {
+	if config.DB != "" {
+		p, err := filepath.Abs(config.DB)
 		if err != nil {
-			return err
+			return "", err
 		}
-		if filepath.Base(path) == "csc.db" {
-			return nil
+		if !create {
+			_, err = os.Stat(p)
+			if err != nil {
+				return "", err
+			}
 		}
-		var dbPath string
-		if config.AbsMode {
-			dbPath, err = filepath.Abs(path)
-		} else {
-			dbPath, err = filepath.Rel(basePath, path)
+		return p, nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for dir := cwd; ; {
+		candidate := filepath.Join(dir, DBFileName)
+		fi, err := os.Stat(candidate)
+		if err == nil && fi.Mode().IsRegular() {
+			return candidate, nil
 		}
-		if err != nil {
-			return err
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
 		}
-		mtime := info.ModTime()
-		size := info.Size()
+		dir = parent
+	}
+	if !create {
+		return "", fmt.Errorf("no %s found in %s or its parents; create one with \"%s scan\" or specify it with --db",
+			DBFileName, cwd, CommandName)
+	}
+	return filepath.Join(cwd, DBFileName), nil
+}
 
-		qs := []qm.QueryMod{
-			qm.Where(models.ObjectColumns.Path+" = ?", dbPath),
+// resolveQueryPath converts a path given on the command line to the form
+// stored in the catalog: relative to the root selected by --root, absolute in
+// abs mode, otherwise relative to dbRoot.
+// A trailing slash is kept so that it still matches directories only.
+func resolveQueryPath(arg string) string {
+	p, err := filepath.Abs(arg)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	base := dbRoot
+	if queryRoot != nil {
+		base = queryRoot.BasePath
+	}
+	if !config.AbsMode || queryRoot != nil {
+		p, err = filepath.Rel(base, p)
+		if err != nil {
+			logrus.Fatal(err)
 		}
-		f, err := models.Objects(qs...).One(ctx, db)
-		if err == nil {
-			if f.Size == -1 {
-				q := qm.WhereIn(models.ObjectColumns.ID+" = ?", f.ID)
-				logrus.Debugf("Updating (size): %s", dbPath)
-				n, err := models.Objects(q).UpdateAll(ctx, db, map[string]interface{}{
-					models.ObjectColumns.Size: size,
-				})
-				if n != 1 {
-					logrus.Warn("invalid number of updated records: " + string(n))
-				}
-				if err != nil {
-					return err
-				}
-				logrus.Debugf("Updated (size): %s", dbPath)
-			}
-			if f.Mtime != mtime {
-				sha256Hex, err := csc.CalcSha256HexString(path)
-				if err != nil {
-					return err
-				}
-				if f.Sha256 != sha256Hex {
-					q := qm.WhereIn(models.ObjectColumns.ID+" = ?", f.ID)
-					logrus.Debugf("Updating: %s", dbPath)
-					n, err := models.Objects(q).UpdateAll(ctx, db, map[string]interface{}{
-						models.ObjectColumns.Type:   "b",
-						models.ObjectColumns.Mtime:  mtime,
-						models.ObjectColumns.Size:   size,
-						models.ObjectColumns.Sha256: sha256Hex,
-					})
-					if n != 1 {
-						logrus.Warn("invalid number of updated records: " + string(n))
-					}
-					if err != nil {
-						return err
-					}
-					logrus.Infof("Updated: %s", dbPath)
-					return nil
-				}
-			}
-		} else {
-			sha256Hex, err := csc.CalcSha256HexString(path)
-			if err != nil {
-				return err
-			}
-			f = &models.Object{
-				Path:      dbPath,
-				Type:      "b",
-				Mtime:     mtime,
-				Size:      size,
-				Sha256:    sha256Hex,
-				Status:    "ok",
-				UpdatedAt: time.Now(),
-			}
-			logrus.Debugf("Inserting: %s", dbPath)
-			err = f.Insert(ctx, db, boil.Infer())
-			if err != nil {
-				// do nothing
-				// return err
-			}
-			logrus.Infof("Inserted: %s", dbPath)
-			return nil
+		p = filepath.ToSlash(p)
+		if p == "." {
+			return ""
 		}
-		return nil
 	}
+	if strings.HasSuffix(arg, "/") && !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return p
+}
+
+// prefixFromArgs returns the resolved first argument, or the current
+// directory if there is none and it is inside the root.
+func prefixFromArgs(args []string) string {
+	if len(args) > 0 {
+		return resolveQueryPath(args[0])
+	}
+	p := resolveQueryPath(".")
+	if strings.HasPrefix(p, "..") {
+		return ""
+	}
+	return p
 }
 
-const initSQL = `CREATE TABLE objects (
-	id INTEGER PRIMARY KEY,
-	path TEXT UNIQUE NOT NULL,
-	type TEXT NOT NULL,
-	size INTEGER NOT NULL,
-	mtime DATETIME NOT NULL,
-	sha256 TEXT NOT NULL,
-	status TEXT NOT NULL,
-	created_at DATETIME NOT NULL,
-	updated_at DATETIME NOT NULL
-);
-CREATE INDEX objects_path ON objects (path); 
-CREATE INDEX objects_sha256_path ON objects (sha256, path);
-CREATE INDEX objects_mtime ON objects (mtime);
-CREATE INDEX objects_updated_at ON objects (updated_at);
-`
+// pathPrefixQueryMod matches the paths starting with prefix. LIKE is not
+// used because SQLite compares it case-insensitively and takes "_" and "%"
+// in prefix as wildcards.
+func pathPrefixQueryMod(prefix string) qm.QueryMod {
+	return qm.Where("substr("+models.ObjectColumns.Path+", 1, ?) = ?", utf8.RuneCountInString(prefix), prefix)
+}
+
+// interruptContext returns a context canceled by the first SIGINT or
+// SIGTERM. Later signals terminate the process as usual. Only commands which
+// stop at the cancellation should install it.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-ch
+		signal.Stop(ch)
+		logrus.Warnf("Received %v; stopping", sig)
+		cancel()
+	}()
+	return ctx
+}
 
+// prepare opens the existing csc.db.
 func prepare() (context.Context, *sql.DB) {
+	return prepareDB(false)
+}
+
+// prepareDB opens csc.db, which is created if create is set and it doesn't
+// exist.
+func prepareDB(create bool) (context.Context, *sql.DB) {
 	ctx := context.Background()
-	dbName := "csc.db"
-	db, err := sql.Open("sqlite3", dbName)
+	dbPath, err := findDBPath(create)
 	if err != nil {
 		logrus.Fatal(err)
 	}
-	return ctx, db
-}
-
-func scan(cmd *cobra.Command, args []string) {
-	ctx, db := prepare()
-	defer db.Close()
-
-	_, err := models.Objects().Count(ctx, db)
+	dbRoot = filepath.Dir(dbPath)
+	logrus.Debugf("Using DB: %s", dbPath)
+	// WAL lets scans commit batches without blocking readers and with
+	// fewer fsyncs
+	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=5000")
 	if err != nil {
-		_, err := db.Exec(initSQL)
-		if err != nil {
-			logrus.Fatal(err)
-		}
+		logrus.Fatal(err)
 	}
-
-	for _, arg := range args {
-		err = filepath.Walk(arg, buildWalkFunc(ctx, db, arg))
-		if err != nil {
-			logrus.Fatal(err)
-		}
+	err = csc.MigrateCscDB(ctx, db)
+	if err != nil {
+		logrus.Fatal(err)
 	}
+	prepareRoot(ctx, db)
+	return ctx, db
+}
+
+// checkpointWAL moves the writes in csc.db-wal into csc.db, which is all
+// that the rsync and http transports of cscman copy.
+func checkpointWAL(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)")
+	return err
 }
 
 func sha256(cmd *cobra.Command, args []string) {
@@ -176,14 +209,14 @@ func sha256(cmd *cobra.Command, args []string) {
 	defer db.Close()
 
 	for _, arg := range args {
-		fs, err := models.Objects(
+		fs, err := models.Objects(append(rootQueryModsIfSelected(),
 			qm.Where(models.ObjectColumns.Sha256+" LIKE ?", arg+"%"),
-			qm.OrderBy(models.ObjectColumns.Sha256+","+models.ObjectColumns.Path)).All(ctx, db)
+			qm.OrderBy(models.ObjectColumns.Sha256+","+models.ObjectColumns.Path))...).All(ctx, db)
 		if err != nil {
 			logrus.Fatal(err)
 		}
 		for _, f := range fs {
-			fmt.Printf("%s\t%s\n", f.Sha256, f.Path)
+			fmt.Printf("%s\t%s\n", f.Sha256, displayPath(f))
 		}
 	}
 }
@@ -193,9 +226,9 @@ func path(cmd *cobra.Command, args []string) {
 	defer db.Close()
 
 	for _, arg := range args {
-		fs, err := models.Objects(
-			qm.Where(models.ObjectColumns.Path+" LIKE ?", arg+"%"),
-			qm.OrderBy(models.ObjectColumns.Path)).All(ctx, db)
+		fs, err := models.Objects(append(rootQueryMods(),
+			pathPrefixQueryMod(resolveQueryPath(arg)),
+			qm.OrderBy(models.ObjectColumns.Path))...).All(ctx, db)
 		if err != nil {
 			logrus.Fatal(err)
 		}
@@ -205,38 +238,6 @@ func path(cmd *cobra.Command, args []string) {
 	}
 }
 
-func find(cmd *cobra.Command, args []string) {
-	ctx, db := prepare()
-	defer db.Close()
-
-	sha256hexs := make([]interface{}, 0)
-	for _, arg := range args {
-		sha256hex, err := csc.CalcSha256HexString(arg)
-		if err != nil {
-			logrus.Error(err)
-			continue
-		}
-		sha256hexs = append(sha256hexs, sha256hex)
-	}
-	fs, err := models.Objects(
-		qm.WhereIn(models.ObjectColumns.Sha256+" IN ?", sha256hexs...),
-		qm.OrderBy(models.ObjectColumns.Path)).All(ctx, db)
-	if err != nil {
-		logrus.Fatal(err)
-	}
-	for _, f := range fs {
-		fmt.Printf("%s\t%s\n", f.Sha256, f.Path)
-	}
-}
-
-const ScanCommandName = "scan"
-
-var ScanCommand = &cobra.Command{
-	Use:  ScanCommandName,
-	Args: cobra.ArbitraryArgs,
-	Run:  scan,
-}
-
 const Sha256CommandName = "sha256"
 
 var Sha256Command = &cobra.Command{
@@ -253,14 +254,6 @@ var PathCommand = &cobra.Command{
 	Run:  path,
 }
 
-const FindCommandName = "find"
-
-var FindCommand = &cobra.Command{
-	Use:  FindCommandName,
-	Args: cobra.ArbitraryArgs,
-	Run:  find,
-}
-
 const CommandName = "csc"
 
 var Command = &cobra.Command{
@@ -268,12 +261,18 @@ var Command = &cobra.Command{
 }
 
 func init() {
-	Command.AddCommand(ScanCommand, Sha256Command, PathCommand, FindCommand)
+	Command.AddCommand(ScanCommand, Sha256Command, PathCommand, FindCommand, DuCommand, StatsCommand, ReportCommand,
+		ExportCommand, ImportManifestCommand, CheckCommand, RootCommand, RunsCommand,
+		ErrorsCommand, PushCommand)
+	addRootFlag(ScanCommand, Sha256Command, PathCommand, FindCommand, DuCommand, StatsCommand, ReportCommand, ExportCommand,
+		ImportManifestCommand, ErrorsCommand)
 	Command.PersistentFlags().StringVarP(&configFile, "config", "c", "", `config file (default "`+CommandName+`.yml")`)
 	Command.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 	Command.PersistentFlags().BoolVar(&debug, "debug", false, "debug output")
 	Command.PersistentFlags().BoolVarP(&version, "version", "V", false, "show Version")
 	Command.Flags().BoolP("abs-mode", "A", false, "absolute path mode")
+	Command.PersistentFlags().String("db", "", `path to csc.db (default: nearest "`+DBFileName+`" in the current or a parent directory)`)
+	viper.BindPFlag("db", Command.PersistentFlags().Lookup("db"))
 
 	for _, s := range []string{"abs-mode"} {
 		envKey := strcase.ToSnake(s)
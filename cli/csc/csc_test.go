@@ -0,0 +1,168 @@
+package csc
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/taskie/csc"
+	"github.com/taskie/csc/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+// openTestDB creates a migrated csc.db with objects of the given sizes by
+// path under management_id 0.
+func openTestDB(t *testing.T, sizes map[string]int64) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), DBFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	err = csc.MigrateCscDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for path, size := range sizes {
+		_, err = db.ExecContext(ctx, scanInsertSQL, 0, path, "b", size, now, now.UnixNano(), "sha256-of-"+path, "ok", now, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	queryRoot = nil
+	return db
+}
+
+func TestPathPrefixQueryMod(t *testing.T) {
+	db := openTestDB(t, map[string]int64{"foo/x": 1, "Foo/y": 1, "foobar/z": 1, "a_b/w": 1, "axb/z": 1, "100%/v": 1, "1000/u": 1})
+	cases := []struct {
+		prefix string
+		want   []string
+	}{
+		{"foo/", []string{"foo/x"}},
+		{"foo", []string{"foo/x", "foobar/z"}},
+		{"Foo/", []string{"Foo/y"}},
+		{"a_b/", []string{"a_b/w"}},
+		{"100%/", []string{"100%/v"}},
+		{"", []string{"100%/v", "1000/u", "Foo/y", "a_b/w", "axb/z", "foo/x", "foobar/z"}},
+	}
+	for _, c := range cases {
+		fs, err := models.Objects(pathPrefixQueryMod(c.prefix), qm.OrderBy(models.ObjectColumns.Path)).All(context.Background(), db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]string, len(fs))
+		for i, f := range fs {
+			got[i] = f.Path
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("prefix %q: paths = %q, want %q", c.prefix, got, c.want)
+		}
+	}
+}
+
+func TestFindDBPath(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(root, "a", "b")
+	err = os.MkdirAll(sub, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.DB = ""
+
+	_, err = findDBPath(false)
+	if err == nil {
+		t.Error("a missing csc.db is found")
+	}
+	p, err := findDBPath(true)
+	if err != nil || p != filepath.Join(sub, DBFileName) {
+		t.Errorf("findDBPath(true) = %q, %v", p, err)
+	}
+	_, err = os.Stat(p)
+	if !os.IsNotExist(err) {
+		t.Errorf("csc.db is created by findDBPath: %v", err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(root, DBFileName), nil, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, create := range []bool{false, true} {
+		p, err = findDBPath(create)
+		if err != nil || p != filepath.Join(root, DBFileName) {
+			t.Errorf("findDBPath(%v) = %q, %v", create, p, err)
+		}
+	}
+
+	config.DB = filepath.Join(root, "other.db")
+	defer func() { config.DB = "" }()
+	_, err = findDBPath(false)
+	if err == nil {
+		t.Error("a missing --db is accepted")
+	}
+}
+
+func TestCheckpointWAL(t *testing.T) {
+	p := filepath.Join(t.TempDir(), DBFileName)
+	db, err := sql.Open("sqlite3", p+"?_journal_mode=WAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	err = csc.MigrateCscDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(p + "-wal")
+	if err != nil || fi.Size() == 0 {
+		t.Fatalf("nothing is written to the WAL: %v", err)
+	}
+	err = checkpointWAL(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err = os.Stat(p + "-wal")
+	if err == nil && fi.Size() != 0 {
+		t.Errorf("%d bytes are left in the WAL", fi.Size())
+	}
+
+	// csc.db alone has the writes
+	cp := filepath.Join(t.TempDir(), DBFileName)
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(cp, b, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cdb, err := sql.Open("sqlite3", cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cdb.Close()
+	_, err = models.Objects().Count(ctx, cdb)
+	if err != nil {
+		t.Errorf("the copy lacks objects: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package csc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/taskie/csc/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+type duEntry struct {
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+	UniqueSize int64  `json:"unique_size"`
+	Count      int64  `json:"count"`
+	parent     string
+	hashes     map[string]struct{}
+}
+
+// splitDir returns the directory components of path relative to prefix.
+// ok is false if path is not under prefix.
+func splitDir(prefix string, path string) (dirs []string, ok bool) {
+	rest := path
+	if prefix != "" {
+		if !strings.HasPrefix(path, prefix) {
+			return nil, false
+		}
+		rest = path[len(prefix):]
+		if rest != "" && rest[0] != '/' && !strings.HasSuffix(prefix, "/") {
+			return nil, false
+		}
+		rest = strings.TrimPrefix(rest, "/")
+	}
+	// the leading slash of an absolute path is kept when there is no prefix
+	rest = strings.TrimSuffix(rest, "/")
+	i := strings.LastIndexByte(rest, '/')
+	if i < 0 {
+		return nil, true
+	}
+	return strings.Split(rest[:i], "/"), true
+}
+
+func joinDir(prefix string, dirs []string) string {
+	if len(dirs) == 0 {
+		if prefix == "" {
+			return "."
+		}
+		return prefix
+	}
+	rel := strings.Join(dirs, "/")
+	if prefix == "" {
+		return rel
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + rel
+}
+
+// calcDiskUsage aggregates the sizes of objects under prefix per directory
+// down to depth levels. A negative depth means unlimited.
+func calcDiskUsage(ctx context.Context, db *sql.DB, prefix string, depth int, unique bool) ([]*duEntry, error) {
+	rows, err := models.Objects(append(rootQueryMods(),
+		qm.Select(models.ObjectColumns.Path, models.ObjectColumns.Size, models.ObjectColumns.Sha256),
+		pathPrefixQueryMod(prefix))...).QueryContext(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entryMap := make(map[string]*duEntry)
+	for rows.Next() {
+		var path, sha256Hex string
+		var size int64
+		err = rows.Scan(&path, &size, &sha256Hex)
+		if err != nil {
+			return nil, err
+		}
+		// absolute paths are grouped under "/" instead of "."
+		base := prefix
+		if base == "" && strings.HasPrefix(path, "/") {
+			base = "/"
+		}
+		dirs, ok := splitDir(base, path)
+		if !ok {
+			continue
+		}
+		if size < 0 {
+			size = 0
+		}
+		for d := 0; d <= len(dirs) && (depth < 0 || d <= depth); d++ {
+			key := joinDir(base, dirs[:d])
+			e, ok := entryMap[key]
+			if !ok {
+				e = &duEntry{Path: key}
+				if d > 0 {
+					e.parent = joinDir(base, dirs[:d-1])
+				}
+				if unique {
+					e.hashes = make(map[string]struct{})
+				}
+				entryMap[key] = e
+			}
+			e.Size += size
+			e.Count++
+			if unique {
+				if _, ok := e.hashes[sha256Hex]; !ok {
+					e.hashes[sha256Hex] = struct{}{}
+					e.UniqueSize += size
+				}
+			}
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*duEntry, 0, len(entryMap))
+	for _, e := range entryMap {
+		e.hashes = nil
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func sortDiskUsage(entries []*duEntry, key string) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch key {
+		case "unique":
+			if a.UniqueSize != b.UniqueSize {
+				return a.UniqueSize > b.UniqueSize
+			}
+		case "count":
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+		case "path":
+			return a.Path < b.Path
+		default:
+			if a.Size != b.Size {
+				return a.Size > b.Size
+			}
+		}
+		return a.Path < b.Path
+	})
+}
+
+var (
+	duDepth  int
+	duUnique bool
+	duSort   string
+)
+
+func du(cmd *cobra.Command, args []string) {
+	ctx, db := prepare()
+	defer db.Close()
+
+	prefix := prefixFromArgs(args)
+	entries, err := calcDiskUsage(ctx, db, prefix, duDepth, duUnique)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	sortDiskUsage(entries, duSort)
+	for _, e := range entries {
+		if duUnique {
+			fmt.Printf("%d\t%d\t%d\t%s\n", e.Size, e.UniqueSize, e.Count, e.Path)
+		} else {
+			fmt.Printf("%d\t%d\t%s\n", e.Size, e.Count, e.Path)
+		}
+	}
+}
+
+const DuCommandName = "du"
+
+var DuCommand = &cobra.Command{
+	Use:  DuCommandName + " [PREFIX]",
+	Args: cobra.MaximumNArgs(1),
+	Run:  du,
+}
+
+func init() {
+	DuCommand.Flags().IntVarP(&duDepth, "depth", "d", 1, "max depth of directories to show (-1 for unlimited)")
+	DuCommand.Flags().BoolVarP(&duUnique, "unique", "u", false, "show unique bytes deduplicated by sha256")
+	DuCommand.Flags().StringVarP(&duSort, "sort", "s", "size", "sort key (size, unique, count, path)")
+}
@@ -0,0 +1,46 @@
+package csc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSplitDir(t *testing.T) {
+	cases := []struct {
+		prefix string
+		path   string
+		dirs   []string
+		ok     bool
+	}{
+		{"", "a/b/c", []string{"a", "b"}, true},
+		{"", "c", nil, true},
+		{"a", "a/b/c", []string{"b"}, true},
+		{"a/", "a/b/c", []string{"b"}, true},
+		{"a", "ab/c", nil, false},
+		{"/", "/home/a/x", []string{"home", "a"}, true},
+		{"/home", "/home/a/x", []string{"a"}, true},
+	}
+	for _, c := range cases {
+		dirs, ok := splitDir(c.prefix, c.path)
+		if !reflect.DeepEqual(dirs, c.dirs) || ok != c.ok {
+			t.Errorf("splitDir(%q, %q) = %q, %v, want %q, %v", c.prefix, c.path, dirs, ok, c.dirs, c.ok)
+		}
+	}
+}
+
+func TestCalcDiskUsage(t *testing.T) {
+	db := openTestDB(t, map[string]int64{"/home/a/x": 1, "/home/a/y": 2, "/home/b": 4, "/etc/z": 8})
+	entries, err := calcDiskUsage(context.Background(), db, "", 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]int64)
+	for _, e := range entries {
+		got[e.Path] = e.Size
+	}
+	want := map[string]int64{"/": 15, "/home": 7, "/home/a": 3, "/etc": 8}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sizes = %v, want %v", got, want)
+	}
+}
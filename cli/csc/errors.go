@@ -0,0 +1,78 @@
+package csc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type unresolvedError struct {
+	ManagementID int64
+	Path         string
+	Message      string
+	CreatedAt    time.Time
+}
+
+// listUnresolvedErrors returns the latest error of each file or directory
+// which no scan has read since, ordered by root and path. If root is given,
+// only the errors under it are listed.
+func listUnresolvedErrors(ctx context.Context, db *sql.DB, root *int64) ([]*unresolvedError, error) {
+	query := `SELECT management_id, path, message, created_at FROM scan_errors e WHERE resolved_at IS NULL
+AND id = (SELECT MAX(id) FROM scan_errors WHERE management_id = e.management_id AND path = e.path)`
+	args := []interface{}{}
+	if root != nil {
+		query += " AND management_id = ?"
+		args = append(args, *root)
+	}
+	rows, err := db.QueryContext(ctx, query+" ORDER BY management_id, path", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	es := make([]*unresolvedError, 0)
+	for rows.Next() {
+		e := &unresolvedError{}
+		err = rows.Scan(&e.ManagementID, &e.Path, &e.Message, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, e)
+	}
+	return es, rows.Err()
+}
+
+// listErrors lists the files and directories which could not be read in
+// their last scan with the error message.
+func listErrors(cmd *cobra.Command, args []string) {
+	ctx, db := prepare()
+	defer db.Close()
+
+	var root *int64
+	if queryRoot != nil {
+		id := queryManagementID()
+		root = &id
+	}
+	es, err := listUnresolvedErrors(ctx, db, root)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	for _, e := range es {
+		path := e.Path
+		if e.ManagementID != 0 {
+			path = managementNames[e.ManagementID] + ":" + path
+		}
+		fmt.Printf("%s\t%s\t%s\n", path, e.CreatedAt.Local().Format(time.RFC3339), e.Message)
+	}
+}
+
+const ErrorsCommandName = "errors"
+
+var ErrorsCommand = &cobra.Command{
+	Use:  ErrorsCommandName,
+	Args: cobra.NoArgs,
+	Run:  listErrors,
+}
@@ -0,0 +1,202 @@
+package csc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/taskie/csc"
+	"github.com/taskie/csc/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+const findBatchSize = 500
+
+type findInput struct {
+	Path   string
+	Sha256 string
+	Err    error
+}
+
+func splitNul(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// readNulSeparatedList reads paths separated by NUL characters like the
+// output of "find -print0".
+func readNulSeparatedList(r io.Reader) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	sc.Split(splitNul)
+	paths := make([]string, 0)
+	for sc.Scan() {
+		if sc.Text() != "" {
+			paths = append(paths, sc.Text())
+		}
+	}
+	return paths, sc.Err()
+}
+
+// hashFindInputs hashes an input. Directories are walked recursively and
+// "-" is read from stdin. A symbolic link given as an input is followed,
+// while those found in a walk are skipped like in scans.
+func hashFindInputs(arg string) []*findInput {
+	if arg == "-" {
+		sha256Hex, err := csc.CalcSha256HexStringFromReader(bufio.NewReader(os.Stdin))
+		return []*findInput{{Path: arg, Sha256: sha256Hex, Err: err}}
+	}
+	fi, err := os.Stat(arg)
+	if err != nil {
+		return []*findInput{{Path: arg, Err: err}}
+	}
+	if !fi.IsDir() {
+		if !fi.Mode().IsRegular() {
+			return []*findInput{{Path: arg, Err: fmt.Errorf("not a regular file: %s", arg)}}
+		}
+		sha256Hex, err := csc.CalcSha256HexString(arg)
+		return []*findInput{{Path: arg, Sha256: sha256Hex, Err: err}}
+	}
+	// the trailing separator lets the walk follow a symbolic link to a
+	// directory
+	root := arg
+	if !os.IsPathSeparator(root[len(root)-1]) {
+		root += string(filepath.Separator)
+	}
+	inputs := make([]*findInput, 0)
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			inputs = append(inputs, &findInput{Path: path, Err: err})
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		sha256Hex, err := csc.CalcSha256HexString(path)
+		inputs = append(inputs, &findInput{Path: path, Sha256: sha256Hex, Err: err})
+		return nil
+	})
+	if err != nil {
+		inputs = append(inputs, &findInput{Path: arg, Err: err})
+	}
+	return inputs
+}
+
+func findObjectsBySha256s(ctx context.Context, db *sql.DB, sha256s []string) (map[string][]*models.Object, error) {
+	objMap := make(map[string][]*models.Object)
+	for i := 0; i < len(sha256s); i += findBatchSize {
+		j := i + findBatchSize
+		if j > len(sha256s) {
+			j = len(sha256s)
+		}
+		sha256Interfaces := make([]interface{}, j-i)
+		for k, sha256Hex := range sha256s[i:j] {
+			sha256Interfaces[k] = sha256Hex
+		}
+		fs, err := models.Objects(append(rootQueryModsIfSelected(),
+			qm.WhereIn(models.ObjectColumns.Sha256+" IN ?", sha256Interfaces...),
+			qm.OrderBy(models.ObjectColumns.Path))...).All(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fs {
+			objMap[f.Sha256] = append(objMap[f.Sha256], f)
+		}
+	}
+	return objMap, nil
+}
+
+var findFromFile string
+
+func find(cmd *cobra.Command, args []string) {
+	ctx, db := prepare()
+	defer db.Close()
+
+	paths := args
+	if findFromFile != "" {
+		var r io.Reader = os.Stdin
+		if findFromFile != "-" {
+			f, err := os.Open(findFromFile)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			defer f.Close()
+			r = f
+		}
+		listed, err := readNulSeparatedList(r)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		paths = append(paths, listed...)
+	}
+
+	inputs := make([]*findInput, 0)
+	for _, path := range paths {
+		inputs = append(inputs, hashFindInputs(path)...)
+	}
+	sha256s := make([]string, 0, len(inputs))
+	seen := make(map[string]struct{})
+	for _, in := range inputs {
+		if in.Err != nil {
+			continue
+		}
+		if _, ok := seen[in.Sha256]; !ok {
+			seen[in.Sha256] = struct{}{}
+			sha256s = append(sha256s, in.Sha256)
+		}
+	}
+	objMap, err := findObjectsBySha256s(ctx, db, sha256s)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	allFound := true
+	for _, in := range inputs {
+		if in.Err != nil {
+			logrus.Error(in.Err)
+			allFound = false
+			continue
+		}
+		objs := objMap[in.Sha256]
+		if len(objs) == 0 {
+			fmt.Printf("%s\t%s\t(not found)\n", in.Path, in.Sha256)
+			allFound = false
+			continue
+		}
+		fmt.Printf("%s\t%s\n", in.Path, in.Sha256)
+		for _, obj := range objs {
+			fmt.Printf("\t%s\n", displayPath(obj))
+		}
+	}
+	if !allFound {
+		db.Close()
+		os.Exit(1)
+	}
+}
+
+const FindCommandName = "find"
+
+var FindCommand = &cobra.Command{
+	Use:  FindCommandName + " [FILE|DIR|-]...",
+	Args: cobra.ArbitraryArgs,
+	Run:  find,
+}
+
+func init() {
+	FindCommand.Flags().StringVarP(&findFromFile, "from-file", "f", "", "read NUL-separated input paths from a file (- for stdin)")
+}
@@ -0,0 +1,55 @@
+package csc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHashFindInputs(t *testing.T) {
+	dir := t.TempDir()
+	err := os.Mkdir(filepath.Join(dir, "d"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"f", "d/g"} {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{"lf": "f", "ld": "d", "d/lg": "g"} {
+		err = os.Symlink(target, filepath.Join(dir, link))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := []struct {
+		arg   string
+		paths []string
+	}{
+		{"f", []string{"f"}},
+		{"lf", []string{"lf"}},
+		{"d", []string{"d/g"}},
+		{"ld", []string{"ld/g"}},
+	}
+	for _, c := range cases {
+		inputs := hashFindInputs(filepath.Join(dir, c.arg))
+		paths := make([]string, 0, len(inputs))
+		for _, in := range inputs {
+			if in.Err != nil || in.Sha256 == "" {
+				t.Errorf("%s: %+v", c.arg, in)
+			}
+			paths = append(paths, filepath.ToSlash(strings.TrimPrefix(in.Path, dir+string(filepath.Separator))))
+		}
+		if strings.Join(paths, ",") != strings.Join(c.paths, ",") {
+			t.Errorf("%s: paths = %q, want %q", c.arg, paths, c.paths)
+		}
+	}
+	inputs := hashFindInputs(filepath.Join(dir, "missing"))
+	if len(inputs) != 1 || inputs[0].Err == nil {
+		t.Errorf("missing: %+v", inputs)
+	}
+}
@@ -0,0 +1,224 @@
+package csc
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/taskie/csc"
+	"github.com/taskie/csc/models"
+	"github.com/volatiletech/sqlboiler/boil"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+// ManifestStatus is the status of objects imported from a manifest, which
+// are known by their checksum but not (yet) seen locally.
+const ManifestStatus = "manifest"
+
+var (
+	exportManifest bool
+	exportFormat   string
+	exportRelative bool
+	exportOutput   string
+)
+
+// relativeToPrefix returns path relative to the directory prefix. A path
+// which is prefix itself becomes its base name, and one which is not under
+// prefix as a directory is returned as it is.
+func relativeToPrefix(prefix string, p string) string {
+	dir := strings.TrimSuffix(prefix, "/")
+	switch {
+	case dir == "":
+		return p
+	case p == dir:
+		return p[strings.LastIndex(p, "/")+1:]
+	case strings.HasPrefix(p, dir+"/"):
+		return p[len(dir)+1:]
+	}
+	return p
+}
+
+func export(cmd *cobra.Command, args []string) {
+	ctx, db := prepare()
+	defer db.Close()
+
+	if exportManifest && exportFormat != csc.ManifestFormatGNU && exportFormat != csc.ManifestFormatBSD {
+		logrus.Fatalf("unknown manifest format: %s", exportFormat)
+	}
+	prefix := prefixFromArgs(args)
+	var w io.Writer = os.Stdout
+	if exportOutput != "" && exportOutput != "-" {
+		f, err := os.Create(exportOutput)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	rows, err := models.Objects(append(rootQueryMods(),
+		qm.Select(models.ObjectColumns.Path, models.ObjectColumns.Size, models.ObjectColumns.Sha256),
+		pathPrefixQueryMod(prefix),
+		qm.OrderBy(models.ObjectColumns.Path))...).QueryContext(ctx, db)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var path, sha256Hex string
+		var size int64
+		err = rows.Scan(&path, &size, &sha256Hex)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if sha256Hex == "" {
+			continue
+		}
+		if exportRelative {
+			path = relativeToPrefix(prefix, path)
+		}
+		if exportManifest {
+			err = csc.WriteManifestEntry(bw, exportFormat, &csc.ManifestEntry{Path: path, Sha256: sha256Hex})
+		} else {
+			_, err = fmt.Fprintf(bw, "%s\t%d\t%s\n", sha256Hex, size, path)
+		}
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+const ExportCommandName = "export"
+
+var ExportCommand = &cobra.Command{
+	Use:  ExportCommandName + " [PREFIX]",
+	Args: cobra.MaximumNArgs(1),
+	Run:  export,
+}
+
+type importResult struct {
+	Inserted, Updated, Skipped int
+}
+
+// manifestEntryPath joins the path of a manifest entry to dir. Absolute
+// paths and paths leading out of dir are rejected.
+func manifestEntryPath(dir string, p string) (string, error) {
+	rel := filepath.Clean(filepath.FromSlash(p))
+	if filepath.IsAbs(rel) || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path in the manifest: %s", p)
+	}
+	return filepath.ToSlash(filepath.Join(dir, rel)), nil
+}
+
+// importManifestEntries registers manifest entries as known objects of the
+// root given by managementID. Rows which were scanned locally are left
+// untouched.
+func importManifestEntries(ctx context.Context, db *sql.DB, managementID int64, dir string, entries []*csc.ManifestEntry) (*importResult, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res := &importResult{}
+	for _, e := range entries {
+		path, err := manifestEntryPath(dir, e.Path)
+		if err != nil {
+			return nil, err
+		}
+		f, err := models.Objects(
+			qm.Where(models.ObjectColumns.ManagementID+" = ?", managementID),
+			qm.And(models.ObjectColumns.Path+" = ?", path)).One(ctx, tx)
+		if err == sql.ErrNoRows {
+			f = &models.Object{
+				ManagementID: managementID,
+				Path:         path,
+				Type:         "b",
+				Size:         -1,
+				Mtime:        time.Time{},
+				Sha256:       e.Sha256,
+				Status:       ManifestStatus,
+			}
+			err = insertObject(ctx, tx, f)
+			if err != nil {
+				return nil, err
+			}
+			res.Inserted++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if f.Status != ManifestStatus || f.Sha256 == e.Sha256 {
+			res.Skipped++
+			continue
+		}
+		f.Sha256 = e.Sha256
+		_, err = f.Update(ctx, tx, boil.Whitelist(models.ObjectColumns.Sha256, models.ObjectColumns.UpdatedAt))
+		if err != nil {
+			return nil, err
+		}
+		res.Updated++
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+var importManifestDir string
+
+func importManifest(cmd *cobra.Command, args []string) {
+	ctx, db := prepareDB(true)
+	defer db.Close()
+
+	var r io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer f.Close()
+		r = f
+	}
+	entries, err := csc.ParseManifest(r)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	res, err := importManifestEntries(ctx, db, queryManagementID(), resolveQueryPath(importManifestDir), entries)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	logrus.Infof("Imported: %d inserted, %d updated, %d skipped", res.Inserted, res.Updated, res.Skipped)
+}
+
+const ImportManifestCommandName = "import-manifest"
+
+var ImportManifestCommand = &cobra.Command{
+	Use:  ImportManifestCommandName + " FILE",
+	Args: cobra.ExactArgs(1),
+	Run:  importManifest,
+}
+
+func init() {
+	ExportCommand.Flags().BoolVarP(&exportManifest, "manifest", "m", false, "output as a checksum manifest")
+	ExportCommand.Flags().StringVarP(&exportFormat, "format", "F", csc.ManifestFormatGNU, "manifest format (gnu, bsd)")
+	ExportCommand.Flags().BoolVarP(&exportRelative, "relative", "r", false, "output paths relative to PREFIX")
+	ExportCommand.Flags().StringVarP(&exportOutput, "output", "o", "-", "output file")
+	ImportManifestCommand.Flags().StringVarP(&importManifestDir, "dir", "d", ".", "directory the manifest paths are relative to")
+}
@@ -0,0 +1,98 @@
+package csc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/taskie/csc"
+	"github.com/taskie/csc/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+func TestRelativeToPrefix(t *testing.T) {
+	cases := []struct {
+		prefix string
+		path   string
+		want   string
+	}{
+		{"", "foo/x", "foo/x"},
+		{"foo", "foo/x", "x"},
+		{"foo/", "foo/x/y", "x/y"},
+		{"foo", "foobar/x", "foobar/x"},
+		{"foo/x", "foo/x", "x"},
+		{"/srv/foo", "/srv/foo/x", "x"},
+	}
+	for _, c := range cases {
+		if got := relativeToPrefix(c.prefix, c.path); got != c.want {
+			t.Errorf("relativeToPrefix(%q, %q) = %q, want %q", c.prefix, c.path, got, c.want)
+		}
+	}
+}
+
+func TestManifestEntryPath(t *testing.T) {
+	cases := []struct {
+		dir  string
+		path string
+		want string
+	}{
+		{"", "x", "x"},
+		{"", "./a/../x", "x"},
+		{"vendor", "a/x", "vendor/a/x"},
+		{"vendor/", "a/x", "vendor/a/x"},
+		{"/srv/foo", "x", "/srv/foo/x"},
+		{"vendor", "..x", "vendor/..x"},
+		{"vendor", "../x", ""},
+		{"vendor", "a/../../x", ""},
+		{"vendor", "..", ""},
+		{"vendor", ".", ""},
+		{"vendor", "/etc/x", ""},
+	}
+	for _, c := range cases {
+		got, err := manifestEntryPath(c.dir, c.path)
+		if c.want == "" {
+			if err == nil {
+				t.Errorf("manifestEntryPath(%q, %q) = %q, want an error", c.dir, c.path, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("manifestEntryPath(%q, %q) = %q, %v, want %q", c.dir, c.path, got, err, c.want)
+		}
+	}
+}
+
+func TestImportManifestEntriesIntoRoot(t *testing.T) {
+	db := openTestDB(t, map[string]int64{"x": 1})
+	ctx := context.Background()
+	m, err := addManagement(ctx, db, "photos", "/srv/photos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []*csc.ManifestEntry{{Path: "x", Sha256: "1"}, {Path: "y", Sha256: "2"}}
+	res, err := importManifestEntries(ctx, db, m.ID.Int64, "", entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *res != (importResult{Inserted: 2}) {
+		t.Errorf("imported %+v, want 2 inserted", *res)
+	}
+	n, err := models.Objects(qm.Where(models.ObjectColumns.ManagementID+" = ?", m.ID.Int64)).Count(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("%d objects under the root, want 2", n)
+	}
+
+	_, err = importManifestEntries(ctx, db, 0, "", []*csc.ManifestEntry{{Path: "z", Sha256: "3"}, {Path: "../w", Sha256: "4"}})
+	if err == nil {
+		t.Error("imported a path out of the directory")
+	}
+	n, err = models.Objects().Count(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("%d objects after the rejected import, want 3", n)
+	}
+}
@@ -0,0 +1,56 @@
+package csc
+
+import (
+	"context"
+	"database/sql"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/taskie/csc/cscman"
+)
+
+// pushCatalog pushes the catalog to a namespace of "cscman serve" at url.
+func pushCatalog(ctx context.Context, db *sql.DB, url string) error {
+	token := pushToken
+	if token == "" {
+		token = os.Getenv("CSC_PUSH_TOKEN")
+	}
+	p := &cscman.Pusher{URL: url, Token: token}
+	result, err := p.Push(ctx, db, pushFull)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Pushed to %s: inserted=%d updated=%d deleted=%d", url, result.Inserted, result.Updated, result.Deleted)
+	return nil
+}
+
+func push(cmd *cobra.Command, args []string) {
+	ctx, db := prepare()
+	defer db.Close()
+
+	err := pushCatalog(ctx, db, args[0])
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+var (
+	pushToken string
+	pushFull  bool
+)
+
+const PushCommandName = "push"
+
+var PushCommand = &cobra.Command{
+	Use:  PushCommandName + " URL",
+	Args: cobra.ExactArgs(1),
+	Run:  push,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{PushCommand, ScanCommand} {
+		cmd.Flags().StringVar(&pushToken, "token", "", "token of cscman serve (default: $CSC_PUSH_TOKEN)")
+	}
+	PushCommand.Flags().BoolVar(&pushFull, "full", false, "upload the whole csc.db instead of the changes since the last push")
+}
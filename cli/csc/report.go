@@ -0,0 +1,395 @@
+package csc
+
+import (
+	"context"
+	"database/sql"
+	"html/template"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/taskie/csc/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+type reportNode struct {
+	Name     string        `json:"name"`
+	Path     string        `json:"path"`
+	Size     int64         `json:"size"`
+	Count    int64         `json:"count"`
+	Children []*reportNode `json:"children,omitempty"`
+}
+
+type reportDuplicate struct {
+	*statsDuplicate
+	Paths []string `json:"paths"`
+}
+
+type reportData struct {
+	Title       string             `json:"title"`
+	Prefix      string             `json:"prefix"`
+	GeneratedAt time.Time          `json:"generated_at"`
+	Stats       *catalogStats      `json:"stats"`
+	Tree        *reportNode        `json:"tree"`
+	Duplicates  []*reportDuplicate `json:"duplicates"`
+}
+
+// buildReportTree links the flat du entries into a tree. Bytes of files
+// directly under a directory are represented by a "(files)" child so that
+// the children of every node add up to its size. Relative and absolute paths
+// have a top-level entry each, "." and "/", which are put under an "(all)"
+// node if both exist.
+func buildReportTree(entries []*duEntry) *reportNode {
+	nodeMap := make(map[string]*reportNode, len(entries))
+	var tops []*reportNode
+	for _, e := range entries {
+		nodeMap[e.Path] = &reportNode{Name: e.Path, Path: e.Path, Size: e.Size, Count: e.Count}
+	}
+	for _, e := range entries {
+		node := nodeMap[e.Path]
+		parent, ok := nodeMap[e.parent]
+		if e.parent == "" || !ok {
+			tops = append(tops, node)
+			continue
+		}
+		node.Name = strings.TrimPrefix(strings.TrimPrefix(e.Path, strings.TrimSuffix(e.parent, "/")), "/")
+		parent.Children = append(parent.Children, node)
+	}
+	var root *reportNode
+	switch len(tops) {
+	case 0:
+		return &reportNode{Name: ".", Path: "."}
+	case 1:
+		root = tops[0]
+	default:
+		root = &reportNode{Name: "(all)", Children: tops}
+		for _, top := range tops {
+			root.Size += top.Size
+			root.Count += top.Count
+		}
+	}
+	var fill func(node *reportNode)
+	fill = func(node *reportNode) {
+		if len(node.Children) == 0 {
+			return
+		}
+		var size, count int64
+		for _, child := range node.Children {
+			fill(child)
+			size += child.Size
+			count += child.Count
+		}
+		if node.Size > size {
+			node.Children = append(node.Children, &reportNode{
+				Name:  "(files)",
+				Path:  node.Path,
+				Size:  node.Size - size,
+				Count: node.Count - count,
+			})
+		}
+		sort.Slice(node.Children, func(i, j int) bool {
+			return node.Children[i].Size > node.Children[j].Size
+		})
+	}
+	fill(root)
+	return root
+}
+
+func findDuplicatePaths(ctx context.Context, db *sql.DB, prefix string, dups []*statsDuplicate) ([]*reportDuplicate, error) {
+	if len(dups) == 0 {
+		return []*reportDuplicate{}, nil
+	}
+	sha256Interfaces := make([]interface{}, len(dups))
+	for i, d := range dups {
+		sha256Interfaces[i] = d.Sha256
+	}
+	fs, err := models.Objects(append(rootQueryMods(),
+		pathPrefixQueryMod(prefix),
+		qm.AndIn(models.ObjectColumns.Sha256+" IN ?", sha256Interfaces...),
+		qm.OrderBy(models.ObjectColumns.Path))...).All(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	pathMap := make(map[string][]string)
+	for _, f := range fs {
+		pathMap[f.Sha256] = append(pathMap[f.Sha256], f.Path)
+	}
+	rds := make([]*reportDuplicate, len(dups))
+	for i, d := range dups {
+		rds[i] = &reportDuplicate{statsDuplicate: d, Paths: pathMap[d.Sha256]}
+	}
+	return rds, nil
+}
+
+func buildReport(ctx context.Context, db *sql.DB, prefix string, depth int, top int) (*reportData, error) {
+	st, err := calcStats(ctx, db, prefix, top)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := calcDiskUsage(ctx, db, prefix, depth, false)
+	if err != nil {
+		return nil, err
+	}
+	dups, err := findDuplicatePaths(ctx, db, prefix, st.Duplicates)
+	if err != nil {
+		return nil, err
+	}
+	title := prefix
+	if title == "" {
+		title = "."
+	}
+	return &reportData{
+		Title:       title,
+		Prefix:      prefix,
+		GeneratedAt: st.GeneratedAt,
+		Stats:       st,
+		Tree:        buildReportTree(entries),
+		Duplicates:  dups,
+	}, nil
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(reportHTML))
+
+var (
+	reportHTMLPath string
+	reportDepth    int
+	reportTop      int
+)
+
+func report(cmd *cobra.Command, args []string) {
+	ctx, db := prepare()
+	defer db.Close()
+
+	prefix := prefixFromArgs(args)
+	data, err := buildReport(ctx, db, prefix, reportDepth, reportTop)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	w := os.Stdout
+	if reportHTMLPath != "" && reportHTMLPath != "-" {
+		w, err = os.Create(reportHTMLPath)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer w.Close()
+	}
+	err = reportTemplate.Execute(w, data)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+const ReportCommandName = "report"
+
+var ReportCommand = &cobra.Command{
+	Use:  ReportCommandName + " [PREFIX]",
+	Args: cobra.MaximumNArgs(1),
+	Run:  report,
+}
+
+func init() {
+	ReportCommand.Flags().StringVar(&reportHTMLPath, "html", "-", "output HTML file")
+	ReportCommand.Flags().IntVarP(&reportDepth, "depth", "d", 6, "max depth of directories in the treemap (-1 for unlimited)")
+	ReportCommand.Flags().IntVarP(&reportTop, "top", "n", 20, "number of entries in ranked tables (0 to omit them)")
+}
+
+const reportHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>csc report: {{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; color: #222; }
+h1 { font-size: 1.4em; }
+h2 { font-size: 1.1em; margin-top: 2em; border-bottom: 1px solid #ccc; }
+table { border-collapse: collapse; }
+td, th { padding: 2px 8px; text-align: left; vertical-align: top; }
+td.num { text-align: right; white-space: nowrap; }
+tr:nth-child(even) { background: #f4f4f4; }
+.summary td:first-child { font-weight: bold; }
+#crumbs span { color: #06c; cursor: pointer; }
+#treemap { position: relative; width: 100%; height: 520px; background: #eee; overflow: hidden; }
+#treemap div { position: absolute; box-sizing: border-box; border: 1px solid #fff; overflow: hidden;
+  font-size: 11px; padding: 2px; color: #fff; cursor: pointer; }
+.bar { background: #4a7fb5; height: 12px; display: inline-block; }
+code { font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>csc report: {{.Title}}</h1>
+<p>Generated at {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+
+<h2>Summary</h2>
+<table class="summary">
+<tr><td>Total files</td><td class="num" id="total-files"></td></tr>
+<tr><td>Total size</td><td class="num" id="total-bytes"></td></tr>
+<tr><td>Unique size</td><td class="num" id="unique-bytes"></td></tr>
+<tr><td>Dedup ratio</td><td class="num" id="dedup-ratio"></td></tr>
+</table>
+
+<h2>Directory sizes</h2>
+<p id="crumbs"></p>
+<div id="treemap"></div>
+
+<h2>Top duplicates</h2>
+<table id="duplicates">
+<tr><th>SHA-256</th><th>Copies</th><th>Size</th><th>Wasted</th><th>Paths</th></tr>
+</table>
+
+<h2>Extensions</h2>
+<table id="extensions"></table>
+
+<h2>File sizes</h2>
+<table id="sizes"></table>
+
+<h2>File ages (mtime)</h2>
+<table id="ages"></table>
+
+<script>
+var data = {{.}};
+
+function formatSize(n) {
+  var units = ["B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"];
+  var i = 0;
+  while (n >= 1024 && i < units.length - 1) { n /= 1024; i++; }
+  return (i === 0 ? n : n.toFixed(1)) + " " + units[i];
+}
+
+function el(tag, text, cls) {
+  var e = document.createElement(tag);
+  if (text !== undefined) e.textContent = text;
+  if (cls) e.className = cls;
+  return e;
+}
+
+function row(cells) {
+  var tr = el("tr");
+  cells.forEach(function (c) { tr.appendChild(c); });
+  return tr;
+}
+
+function renderSummary() {
+  var st = data.stats;
+  document.getElementById("total-files").textContent = st.total_files.toLocaleString();
+  document.getElementById("total-bytes").textContent = formatSize(st.total_bytes);
+  document.getElementById("unique-bytes").textContent = formatSize(st.unique_bytes);
+  document.getElementById("dedup-ratio").textContent = st.dedup_ratio.toFixed(3);
+}
+
+function renderBuckets(id, buckets) {
+  var table = document.getElementById(id);
+  var max = 0;
+  (buckets || []).forEach(function (b) { if (b.bytes > max) max = b.bytes; });
+  table.appendChild(row([el("th", ""), el("th", "Files"), el("th", "Size"), el("th", "")]));
+  (buckets || []).forEach(function (b) {
+    var bar = el("span", undefined, "bar");
+    bar.style.width = (max > 0 ? Math.max(1, Math.round(300 * b.bytes / max)) : 0) + "px";
+    var td = el("td");
+    td.appendChild(bar);
+    table.appendChild(row([el("td", b.label), el("td", b.count.toLocaleString(), "num"),
+      el("td", formatSize(b.bytes), "num"), td]));
+  });
+}
+
+function renderDuplicates() {
+  var table = document.getElementById("duplicates");
+  (data.duplicates || []).forEach(function (d) {
+    var paths = el("td");
+    (d.paths || []).forEach(function (p) { paths.appendChild(el("div", p)); });
+    var hash = el("td");
+    hash.appendChild(el("code", d.sha256.substring(0, 16)));
+    hash.title = d.sha256;
+    table.appendChild(row([hash, el("td", d.count, "num"), el("td", formatSize(d.size), "num"),
+      el("td", formatSize(d.wasted_bytes), "num"), paths]));
+  });
+}
+
+// squarify lays out nodes in the rectangle (x, y, w, h) following the
+// squarified treemap algorithm of Bruls, Huizing and van Wijk.
+function squarify(nodes, x, y, w, h, out) {
+  var total = 0;
+  nodes.forEach(function (n) { total += n.size; });
+  if (total <= 0) return;
+  var scale = w * h / total;
+  var items = nodes.filter(function (n) { return n.size > 0; }).map(function (n) {
+    return { node: n, area: n.size * scale };
+  });
+  while (items.length > 0) {
+    var side = Math.min(w, h);
+    var rowItems = [], rowArea = 0, best = Infinity;
+    while (items.length > 0) {
+      var next = items[0];
+      var area = rowArea + next.area;
+      var worst = 0;
+      rowItems.concat([next]).forEach(function (it) {
+        var r = Math.max(side * side * it.area / (area * area), (area * area) / (side * side * it.area));
+        if (r > worst) worst = r;
+      });
+      if (worst > best) break;
+      best = worst;
+      rowItems.push(items.shift());
+      rowArea = area;
+    }
+    var thick = rowArea / side, offset = 0;
+    rowItems.forEach(function (it) {
+      var len = it.area / thick;
+      if (w >= h) {
+        out.push({ node: it.node, x: x, y: y + offset, w: thick, h: len });
+      } else {
+        out.push({ node: it.node, x: x + offset, y: y, w: len, h: thick });
+      }
+      offset += len;
+    });
+    if (w >= h) { x += thick; w -= thick; } else { y += thick; h -= thick; }
+  }
+}
+
+var palette = ["#4a7fb5", "#5b9c5a", "#c5793a", "#8f5fa8", "#b5484a", "#3d9c9c", "#a89a3a", "#6b6b6b"];
+var stack = [];
+
+function renderTreemap() {
+  var node = stack[stack.length - 1];
+  var box = document.getElementById("treemap");
+  box.innerHTML = "";
+  var crumbs = document.getElementById("crumbs");
+  crumbs.innerHTML = "";
+  stack.forEach(function (n, i) {
+    var s = el("span", n.name);
+    s.onclick = function () { stack = stack.slice(0, i + 1); renderTreemap(); };
+    crumbs.appendChild(s);
+    if (i < stack.length - 1) crumbs.appendChild(document.createTextNode(" / "));
+  });
+  crumbs.appendChild(document.createTextNode(" (" + formatSize(node.size) + ", " +
+    node.count.toLocaleString() + " files)"));
+  var rects = [];
+  squarify(node.children || [node], 0, 0, box.clientWidth, box.clientHeight, rects);
+  rects.forEach(function (r, i) {
+    var d = el("div", r.node.name);
+    d.style.left = r.x + "px";
+    d.style.top = r.y + "px";
+    d.style.width = r.w + "px";
+    d.style.height = r.h + "px";
+    d.style.background = palette[i % palette.length];
+    d.title = r.node.path + "\n" + formatSize(r.node.size) + ", " + r.node.count.toLocaleString() + " files";
+    if (r.node.children && r.node.children.length > 0) {
+      d.onclick = function () { stack.push(r.node); renderTreemap(); };
+    }
+    box.appendChild(d);
+  });
+}
+
+renderSummary();
+renderDuplicates();
+renderBuckets("extensions", data.stats.extensions);
+renderBuckets("sizes", data.stats.size_buckets);
+renderBuckets("ages", data.stats.age_buckets);
+stack = [data.tree];
+renderTreemap();
+window.onresize = renderTreemap;
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,55 @@
+package csc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// reportTreeSizes returns the sizes of the nodes of a report tree by their
+// names joined with " > ".
+func reportTreeSizes(node *reportNode, path string, sizes map[string]int64) {
+	if path != "" {
+		path += " > "
+	}
+	path += node.Name
+	sizes[path] = node.Size
+	for _, child := range node.Children {
+		reportTreeSizes(child, path, sizes)
+	}
+}
+
+func TestBuildReportTree(t *testing.T) {
+	cases := []struct {
+		sizes map[string]int64
+		want  map[string]int64
+	}{
+		{
+			map[string]int64{},
+			map[string]int64{".": 0},
+		},
+		{
+			map[string]int64{"a/x": 1, "a/y": 2, "b": 4},
+			map[string]int64{".": 7, ". > a": 3, ". > (files)": 4},
+		},
+		{
+			map[string]int64{"a/x": 1, "/srv/y": 2, "/z": 4},
+			map[string]int64{
+				"(all)": 7, "(all) > .": 1, "(all) > . > a": 1,
+				"(all) > /": 6, "(all) > / > srv": 2, "(all) > / > (files)": 4,
+			},
+		},
+	}
+	for _, c := range cases {
+		db := openTestDB(t, c.sizes)
+		entries, err := calcDiskUsage(context.Background(), db, "", -1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]int64)
+		reportTreeSizes(buildReportTree(entries), "", got)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("tree of %v = %v, want %v", c.sizes, got, c.want)
+		}
+	}
+}
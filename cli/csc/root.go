@@ -0,0 +1,253 @@
+package csc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/taskie/csc/models"
+	"github.com/volatiletech/sqlboiler/boil"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+// listManagements returns the roots ordered by name. A root is a row of
+// managements, and objects under it are keyed by (management_id, path
+// relative to BasePath). The management_id 0 stands for the directory
+// containing csc.db.
+func listManagements(ctx context.Context, db *sql.DB) (models.ManagementSlice, error) {
+	return models.Managements(qm.OrderBy(models.ManagementColumns.Name)).All(ctx, db)
+}
+
+func findManagement(ctx context.Context, db *sql.DB, name string) (*models.Management, error) {
+	m, err := models.Managements(qm.Where(models.ManagementColumns.Name+" = ?", name)).One(ctx, db)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no such root: %s", name)
+	}
+	return m, err
+}
+
+// addManagement registers a root. sqlboiler fails to read back the nullable
+// INTEGER PRIMARY KEY of SQLite like in insertObject, so the root is looked
+// up again by name.
+func addManagement(ctx context.Context, db *sql.DB, name string, basePath string) (*models.Management, error) {
+	now := time.Now()
+	m := &models.Management{
+		Name:      name,
+		BasePath:  basePath,
+		Type:      "local",
+		Status:    "new",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err := m.Insert(ctx, db, boil.Infer())
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return nil, err
+	}
+	return findManagement(ctx, db, name)
+}
+
+// removeLogDeletionsSQL logs the deletions of the objects of a root for
+// incremental syncs. The root is gone when they are synced, so they are
+// logged under management_id 0 with the paths which cscman gives objects
+// under the root.
+const removeLogDeletionsSQL = "INSERT INTO deletions (scan_run_id, management_id, path, deleted_at)" +
+	" SELECT ?, 0, rtrim(?, '/') || '/' || path, ? FROM objects WHERE management_id = ?"
+
+// removeManagement deletes a root together with all of its objects. The
+// deletions are logged by a run of their own.
+func removeManagement(ctx context.Context, db *sql.DB, m *models.Management) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	run, err := createScanRun(ctx, tx, "remove "+m.Name, nil)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, removeLogDeletionsSQL, run.ID, m.BasePath, time.Now(), m.ID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := models.Objects(qm.Where(models.ObjectColumns.ManagementID+" = ?", m.ID)).DeleteAll(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	_, err = m.Delete(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	run.Deleted = n
+	err = finishScanRun(ctx, tx, run, ScanRunOK, 0)
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// touchManagement records the time and result of the last scan of a root.
+func touchManagement(ctx context.Context, db *sql.DB, m *models.Management, mtime time.Time, status string) error {
+	m.Mtime = mtime
+	m.Status = status
+	m.UpdatedAt = time.Now()
+	_, err := m.Update(ctx, db, boil.Whitelist(models.ManagementColumns.Mtime, models.ManagementColumns.Status,
+		models.ManagementColumns.UpdatedAt))
+	return err
+}
+
+var (
+	// rootName is the value of --root of the commands which can select a root.
+	rootName string
+	// queryRoot is the root selected by --root, or nil for management_id 0.
+	queryRoot *models.Management
+	// managementNames maps management_id to root names for display.
+	managementNames map[int64]string
+)
+
+func addRootFlag(cmds ...*cobra.Command) {
+	for _, cmd := range cmds {
+		cmd.Flags().StringVarP(&rootName, "root", "R", "", "name of the root registered by \"root add\"")
+	}
+}
+
+// prepareRoot loads the registered roots and resolves --root.
+func prepareRoot(ctx context.Context, db *sql.DB) {
+	ms, err := listManagements(ctx, db)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	managementNames = make(map[int64]string, len(ms))
+	for _, m := range ms {
+		managementNames[m.ID.Int64] = m.Name
+	}
+	if rootName != "" {
+		queryRoot, err = findManagement(ctx, db, rootName)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	}
+}
+
+func queryManagementID() int64 {
+	if queryRoot == nil {
+		return 0
+	}
+	return queryRoot.ID.Int64
+}
+
+// rootQueryMods restricts a query to the selected root.
+func rootQueryMods() []qm.QueryMod {
+	return []qm.QueryMod{qm.Where(models.ObjectColumns.ManagementID+" = ?", queryManagementID())}
+}
+
+// rootQueryModsIfSelected restricts a query only if --root is given.
+func rootQueryModsIfSelected() []qm.QueryMod {
+	if queryRoot == nil {
+		return []qm.QueryMod{}
+	}
+	return rootQueryMods()
+}
+
+// displayPath prefixes the paths of objects under named roots with
+// "NAME:".
+func displayPath(f *models.Object) string {
+	if f.ManagementID == 0 {
+		return f.Path
+	}
+	return managementNames[f.ManagementID] + ":" + f.Path
+}
+
+func rootAdd(cmd *cobra.Command, args []string) {
+	ctx, db := prepareDB(true)
+	defer db.Close()
+
+	name := args[0]
+	basePath, err := filepath.Abs(args[1])
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	fi, err := os.Stat(basePath)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if !fi.IsDir() {
+		logrus.Fatalf("not a directory: %s", basePath)
+	}
+	_, err = addManagement(ctx, db, name, basePath)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+func rootList(cmd *cobra.Command, args []string) {
+	ctx, db := prepare()
+	defer db.Close()
+
+	ms, err := listManagements(ctx, db)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	for _, m := range ms {
+		var count, size int64
+		err = db.QueryRowContext(ctx,
+			"SELECT COUNT(*), COALESCE(SUM(CASE WHEN size > 0 THEN size ELSE 0 END), 0) FROM objects WHERE management_id = ?",
+			m.ID).Scan(&count, &size)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		scanned := "-"
+		if !m.Mtime.IsZero() {
+			scanned = m.Mtime.Local().Format(time.RFC3339)
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\t%d\t%d\n", m.Name, m.BasePath, m.Status, scanned, count, size)
+	}
+}
+
+func rootRemove(cmd *cobra.Command, args []string) {
+	ctx, db := prepare()
+	defer db.Close()
+
+	m, err := findManagement(ctx, db, args[0])
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	n, err := removeManagement(ctx, db, m)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	logrus.Infof("Removed root %s with %d objects", m.Name, n)
+}
+
+const RootCommandName = "root"
+
+var RootCommand = &cobra.Command{
+	Use: RootCommandName,
+}
+
+var RootAddCommand = &cobra.Command{
+	Use:  "add NAME PATH",
+	Args: cobra.ExactArgs(2),
+	Run:  rootAdd,
+}
+
+var RootListCommand = &cobra.Command{
+	Use:  "list",
+	Args: cobra.NoArgs,
+	Run:  rootList,
+}
+
+var RootRemoveCommand = &cobra.Command{
+	Use:  "remove NAME",
+	Args: cobra.ExactArgs(1),
+	Run:  rootRemove,
+}
+
+func init() {
+	RootCommand.AddCommand(RootAddCommand, RootListCommand, RootRemoveCommand)
+}
@@ -0,0 +1,89 @@
+package csc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/taskie/csc/models"
+)
+
+func TestManagements(t *testing.T) {
+	db := openTestDB(t, nil)
+	ctx := context.Background()
+
+	for _, name := range []string{"photos", "music"} {
+		m, err := addManagement(ctx, db, name, "/srv/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !m.ID.Valid || m.ID.Int64 == 0 || m.Name != name || m.BasePath != "/srv/"+name || m.Status != "new" {
+			t.Errorf("addManagement(%s) = %+v", name, m)
+		}
+	}
+	_, err := addManagement(ctx, db, "music", "/srv/other")
+	if err == nil {
+		t.Error("a duplicate name is accepted")
+	}
+
+	ms, err := listManagements(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 || ms[0].Name != "music" || ms[1].Name != "photos" {
+		t.Fatalf("listManagements() = %+v", ms)
+	}
+
+	m, err := findManagement(ctx, db, "photos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	err = touchManagement(ctx, db, m, mtime, ScanRunOK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err = findManagement(ctx, db, "photos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Mtime.Equal(mtime) || m.Status != ScanRunOK {
+		t.Errorf("touched root: %+v", m)
+	}
+
+	now := time.Now()
+	for _, id := range []int64{0, m.ID.Int64} {
+		_, err = db.ExecContext(ctx, scanInsertSQL, id, "x", "b", 1, now, now.UnixNano(), "sha256-of-x", "ok", now, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := removeManagement(ctx, db, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("removeManagement() deleted %d objects, want 1", n)
+	}
+	_, err = findManagement(ctx, db, "photos")
+	if err == nil {
+		t.Error("the removed root is found")
+	}
+	count, err := models.Objects().Count(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("%d objects are left, want 1", count)
+	}
+	var managementID int64
+	var path, status string
+	err = db.QueryRowContext(ctx, "SELECT d.management_id, d.path, r.status FROM deletions d"+
+		" JOIN scan_runs r ON r.id = d.scan_run_id").Scan(&managementID, &path, &status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if managementID != 0 || path != "/srv/photos/x" || status != ScanRunOK {
+		t.Errorf("logged deletion: %d, %s by a run of %s", managementID, path, status)
+	}
+}
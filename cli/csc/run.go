@@ -0,0 +1,276 @@
+package csc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/volatiletech/sqlboiler/boil"
+)
+
+const (
+	ScanRunRunning = "running"
+	ScanRunOK      = "ok"
+	ScanRunError   = "error"
+	// ScanRunPartial means the scan finished but some paths could not be
+	// read.
+	ScanRunPartial = "partial"
+	// ScanRunInterrupted means the scan was stopped by a signal and can be
+	// continued with "scan --resume".
+	ScanRunInterrupted = "interrupted"
+)
+
+// scanRun is a row of scan_runs which records a run of the scan command.
+type scanRun struct {
+	ID          int64
+	StartedAt   time.Time
+	FinishedAt  *time.Time
+	Roots       string
+	Seen        int64
+	Hashed      int64
+	Inserted    int64
+	Updated     int64
+	Deleted     int64
+	Errored     int64
+	BytesHashed int64
+	Status      string
+	ExitStatus  *int
+	// CheckpointWalk and CheckpointPath tell the last file completed by an
+	// interrupted run: the number of the walk in the run and the path in
+	// the catalog.
+	CheckpointWalk *int
+	CheckpointPath *string
+	ResumedFrom    *int64
+}
+
+const scanRunColumns = "id, started_at, finished_at, roots, seen, hashed, inserted, updated, deleted, errored, bytes_hashed, status, exit_status, checkpoint_walk, checkpoint_path, resumed_from"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanScanRun(row rowScanner) (*scanRun, error) {
+	r := &scanRun{}
+	var finishedAt sql.NullTime
+	var exitStatus, checkpointWalk, resumedFrom sql.NullInt64
+	var checkpointPath sql.NullString
+	err := row.Scan(&r.ID, &r.StartedAt, &finishedAt, &r.Roots, &r.Seen, &r.Hashed, &r.Inserted, &r.Updated,
+		&r.Deleted, &r.Errored, &r.BytesHashed, &r.Status, &exitStatus, &checkpointWalk, &checkpointPath, &resumedFrom)
+	if err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		r.FinishedAt = &finishedAt.Time
+	}
+	if exitStatus.Valid {
+		n := int(exitStatus.Int64)
+		r.ExitStatus = &n
+	}
+	if checkpointWalk.Valid {
+		n := int(checkpointWalk.Int64)
+		r.CheckpointWalk = &n
+	}
+	if checkpointPath.Valid {
+		r.CheckpointPath = &checkpointPath.String
+	}
+	if resumedFrom.Valid {
+		r.ResumedFrom = &resumedFrom.Int64
+	}
+	return r, nil
+}
+
+func createScanRun(ctx context.Context, exec boil.ContextExecutor, roots string, resumedFrom *scanRun) (*scanRun, error) {
+	r := &scanRun{StartedAt: time.Now(), Roots: roots, Status: ScanRunRunning}
+	if resumedFrom != nil {
+		r.ResumedFrom = &resumedFrom.ID
+	}
+	res, err := exec.ExecContext(ctx, "INSERT INTO scan_runs (started_at, roots, status, resumed_from) VALUES (?, ?, ?, ?)",
+		r.StartedAt, r.Roots, r.Status, r.ResumedFrom)
+	if err != nil {
+		return nil, err
+	}
+	r.ID, err = res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// saveScanRun writes the counters and status of r.
+func saveScanRun(ctx context.Context, exec boil.ContextExecutor, r *scanRun) error {
+	_, err := exec.ExecContext(ctx,
+		`UPDATE scan_runs SET finished_at = ?, seen = ?, hashed = ?, inserted = ?, updated = ?, deleted = ?,
+errored = ?, bytes_hashed = ?, status = ?, exit_status = ?, checkpoint_walk = ?, checkpoint_path = ? WHERE id = ?`,
+		r.FinishedAt, r.Seen, r.Hashed, r.Inserted, r.Updated, r.Deleted, r.Errored, r.BytesHashed,
+		r.Status, r.ExitStatus, r.CheckpointWalk, r.CheckpointPath, r.ID)
+	return err
+}
+
+// finishScanRun marks r as finished with the given exit status.
+func finishScanRun(ctx context.Context, exec boil.ContextExecutor, r *scanRun, status string, exitStatus int) error {
+	now := time.Now()
+	r.FinishedAt = &now
+	r.Status = status
+	r.ExitStatus = &exitStatus
+	return saveScanRun(ctx, exec, r)
+}
+
+func addScanError(ctx context.Context, exec boil.ContextExecutor, r *scanRun, managementID int64, path string, message string) error {
+	r.Errored++
+	_, err := exec.ExecContext(ctx,
+		"INSERT INTO scan_errors (scan_run_id, management_id, path, message, created_at) VALUES (?, ?, ?, ?, ?)",
+		r.ID, managementID, path, message, time.Now())
+	return err
+}
+
+// resolveScanErrors marks the errors of the runs before the run since under
+// prefix as resolved, except for those under the skipped directories.
+func resolveScanErrors(ctx context.Context, exec boil.ContextExecutor, since int64, managementID int64, prefix string,
+	skipped []string) error {
+	query := "UPDATE scan_errors SET resolved_at = ? WHERE management_id = ? AND scan_run_id < ? AND resolved_at IS NULL"
+	args := []interface{}{time.Now(), managementID, since}
+	if clause, clauseArgs := underPathClause(prefix); clause != "" {
+		query += " AND " + clause
+		args = append(args, clauseArgs...)
+	}
+	for _, dir := range skipped {
+		clause, clauseArgs := underPathClause(dir)
+		if clause == "" {
+			return nil
+		}
+		query += " AND NOT " + clause
+		args = append(args, clauseArgs...)
+	}
+	_, err := exec.ExecContext(ctx, query, args...)
+	return err
+}
+
+func listScanRuns(ctx context.Context, db *sql.DB, limit int) ([]*scanRun, error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+scanRunColumns+" FROM scan_runs ORDER BY id DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	rs := make([]*scanRun, 0)
+	for rows.Next() {
+		r, err := scanScanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+	return rs, rows.Err()
+}
+
+// findResumableScanRun returns the latest run if it was interrupted.
+func findResumableScanRun(ctx context.Context, db *sql.DB) (*scanRun, error) {
+	rs, err := listScanRuns(ctx, db, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(rs) == 0 || rs[0].Status != ScanRunInterrupted || rs[0].CheckpointWalk == nil {
+		return nil, fmt.Errorf("no interrupted scan to resume")
+	}
+	return rs[0], nil
+}
+
+// firstResumedScanRun follows the runs resumed by r back to the one which
+// started the scan and returns its ID.
+func firstResumedScanRun(ctx context.Context, db *sql.DB, r *scanRun) (int64, error) {
+	for r.ResumedFrom != nil {
+		var err error
+		r, err = findScanRun(ctx, db, *r.ResumedFrom)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return r.ID, nil
+}
+
+func findScanRun(ctx context.Context, db *sql.DB, id int64) (*scanRun, error) {
+	r, err := scanScanRun(db.QueryRowContext(ctx, "SELECT "+scanRunColumns+" FROM scan_runs WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no such scan run: %d", id)
+	}
+	return r, err
+}
+
+func printScanRun(r *scanRun) {
+	duration := "-"
+	if r.FinishedAt != nil {
+		duration = r.FinishedAt.Sub(r.StartedAt).Round(time.Millisecond).String()
+	}
+	exitStatus := "-"
+	if r.ExitStatus != nil {
+		exitStatus = strconv.Itoa(*r.ExitStatus)
+	}
+	roots := r.Roots
+	if r.ResumedFrom != nil {
+		roots += fmt.Sprintf(" (resumed #%d)", *r.ResumedFrom)
+	}
+	fmt.Printf("%d\t%s\t%s\t%s\t%s\tseen=%d hashed=%d inserted=%d updated=%d deleted=%d errored=%d bytes=%d\t%s\n",
+		r.ID, r.StartedAt.Local().Format(time.RFC3339), duration, r.Status, exitStatus,
+		r.Seen, r.Hashed, r.Inserted, r.Updated, r.Deleted, r.Errored, r.BytesHashed, roots)
+}
+
+var runsLimit int
+
+func runs(cmd *cobra.Command, args []string) {
+	ctx, db := prepare()
+	defer db.Close()
+
+	if len(args) > 0 {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		r, err := findScanRun(ctx, db, id)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		printScanRun(r)
+		rows, err := db.QueryContext(ctx,
+			"SELECT management_id, path, message FROM scan_errors WHERE scan_run_id = ? ORDER BY id", id)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var managementID int64
+			var path, message string
+			err = rows.Scan(&managementID, &path, &message)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			if managementID != 0 {
+				path = managementNames[managementID] + ":" + path
+			}
+			fmt.Printf("\t%s\t%s\n", path, message)
+		}
+		return
+	}
+
+	rs, err := listScanRuns(ctx, db, runsLimit)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	for _, r := range rs {
+		printScanRun(r)
+	}
+}
+
+const RunsCommandName = "runs"
+
+var RunsCommand = &cobra.Command{
+	Use:  RunsCommandName + " [ID]",
+	Args: cobra.MaximumNArgs(1),
+	Run:  runs,
+}
+
+func init() {
+	RunsCommand.Flags().IntVarP(&runsLimit, "limit", "n", 20, "number of runs to show")
+}
@@ -0,0 +1,638 @@
+package csc
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/taskie/csc"
+	"github.com/taskie/csc/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+// scanTarget tells where scanned files are stored in the catalog.
+type scanTarget struct {
+	ManagementID int64
+	BasePath     string
+	AbsMode      bool
+}
+
+func (t *scanTarget) dbPath(path string) (string, error) {
+	p, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if t.AbsMode {
+		return p, nil
+	}
+	p, err = filepath.Rel(t.BasePath, p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(p), nil
+}
+
+// UnreadableStatus is the status of objects which could not be read in
+// the last scan. They keep the last known hash if any.
+const UnreadableStatus = "unreadable"
+
+// unreadableError is an error reading a scanned file, as opposed to a
+// catalog error. The scan records it and goes on.
+type unreadableError struct {
+	error
+}
+
+// scanner walks files and brings the objects table in sync with them,
+// counting what it did in a scan run.
+type scanner struct {
+	ctx context.Context
+	db  *sql.DB
+	run *scanRun
+	// interrupt is canceled to stop the scan after the current file.
+	interrupt context.Context
+	// resume is the interrupted run to continue, if any.
+	resume *scanRun
+	// since is the first run resumed by this one, or this run. The walks
+	// of the runs since then cover the tree together.
+	since int64
+	// walks counts the walks of the run, and lastWalk and lastPath tell
+	// the last file completed for checkpointing.
+	walks    int
+	lastWalk int
+	lastPath string
+	// batch writes to the catalog, and dirs holds the objects of the
+	// directories being walked.
+	batch *scanBatch
+	dirs  []*scanDir
+}
+
+// checkpoint saves where an interrupted scan stopped.
+func (s *scanner) checkpoint() {
+	s.run.CheckpointWalk = &s.lastWalk
+	s.run.CheckpointPath = &s.lastPath
+}
+
+// compareWalkOrder compares slash-separated paths in the order
+// filepath.Walk visits them: by components, directories before their
+// contents.
+func compareWalkOrder(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "." {
+		return -1
+	}
+	if b == "." {
+		return 1
+	}
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] != bs[i] {
+			if as[i] < bs[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	if len(as) < len(bs) {
+		return -1
+	}
+	return 1
+}
+
+// isAncestor reports whether dir contains path.
+func isAncestor(dir, path string) bool {
+	return dir == "." || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/")
+}
+
+func (s *scanner) hash(path string, size int64) (string, error) {
+	sha256Hex, err := csc.CalcSha256HexString(path)
+	if err != nil {
+		return "", &unreadableError{err}
+	}
+	s.run.Hashed++
+	s.run.BytesHashed += size
+	return sha256Hex, nil
+}
+
+// scanDir holds the objects directly under a directory being walked.
+type scanDir struct {
+	path    string
+	objects map[string]*models.Object
+}
+
+// loadDir preloads the objects under the directory dbPath. Directories
+// which the walk has left are dropped.
+func (s *scanner) loadDir(target *scanTarget, dbPath string) error {
+	for len(s.dirs) != 0 && !isAncestor(s.dirs[len(s.dirs)-1].path, dbPath) {
+		s.dirs = s.dirs[:len(s.dirs)-1]
+	}
+	fs, err := s.batch.selectDir(target.ManagementID, dbPath)
+	if err != nil {
+		return err
+	}
+	d := &scanDir{path: dbPath, objects: make(map[string]*models.Object, len(fs))}
+	for _, f := range fs {
+		d.objects[f.Path] = f
+	}
+	s.dirs = append(s.dirs, d)
+	return nil
+}
+
+func parentDir(dbPath string) string {
+	i := strings.LastIndex(dbPath, "/")
+	switch {
+	case i < 0:
+		return "."
+	case i == 0:
+		return "/"
+	default:
+		return dbPath[:i]
+	}
+}
+
+// lookup returns the object at dbPath, or nil if there is none.
+func (s *scanner) lookup(target *scanTarget, dbPath string) (*models.Object, error) {
+	parent := parentDir(dbPath)
+	for i := len(s.dirs) - 1; i >= 0; i-- {
+		if s.dirs[i].path == parent {
+			return s.dirs[i].objects[dbPath], nil
+		}
+	}
+	return s.batch.selectOne(target.ManagementID, dbPath)
+}
+
+// sameMtime reports whether f was scanned at mtime. Objects from before
+// mtime_ns was added are compared by the DATETIME column, which is exact
+// in SQLite but may be in another zone.
+func sameMtime(f *models.Object, mtime time.Time) bool {
+	if f.MtimeNs != 0 {
+		return f.MtimeNs == mtime.UnixNano()
+	}
+	return f.Mtime.Equal(mtime)
+}
+
+func (s *scanner) visit(target *scanTarget, path string, dbPath string, info os.FileInfo) error {
+	mtime := info.ModTime().UTC()
+	mtimeNs := mtime.UnixNano()
+	size := info.Size()
+
+	f, err := s.lookup(target, dbPath)
+	if err != nil {
+		return err
+	}
+	if f != nil {
+		if f.Status == "ok" && f.Size == size && sameMtime(f, mtime) {
+			if f.MtimeNs == mtimeNs {
+				return nil
+			}
+			logrus.Debugf("Updating (mtime_ns): %s", dbPath)
+			return s.batch.exec(scanUpdateMetaSQL, size, mtime, mtimeNs, time.Now(), f.ID)
+		}
+		sha256Hex, err := s.hash(path, size)
+		if err != nil {
+			return err
+		}
+		if f.Sha256 == sha256Hex && f.Status == "ok" {
+			logrus.Debugf("Updating (metadata): %s", dbPath)
+			err = s.batch.exec(scanUpdateMetaSQL, size, mtime, mtimeNs, time.Now(), f.ID)
+			if err != nil {
+				return err
+			}
+			s.run.Updated++
+			logrus.Debugf("Updated (metadata): %s", dbPath)
+			return nil
+		}
+		logrus.Debugf("Updating: %s", dbPath)
+		err = s.batch.exec(scanUpdateSQL, "b", size, mtime, mtimeNs, sha256Hex, "ok", time.Now(), f.ID)
+		if err != nil {
+			return err
+		}
+		s.run.Updated++
+		logrus.Infof("Updated: %s", dbPath)
+		return nil
+	}
+
+	sha256Hex, err := s.hash(path, size)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	logrus.Debugf("Inserting: %s", dbPath)
+	err = s.batch.exec(scanInsertSQL, target.ManagementID, dbPath, "b", size, mtime, mtimeNs, sha256Hex, "ok", now, now)
+	if err != nil {
+		return err
+	}
+	s.run.Inserted++
+	logrus.Infof("Inserted: %s", dbPath)
+	return nil
+}
+
+// markUnreadable records an error reading path, which "csc errors" lists
+// until a later scan reads it, and sets the status of its object to
+// UnreadableStatus. An object is created if there is none except for
+// directories.
+func (s *scanner) markUnreadable(target *scanTarget, dbPath string, info os.FileInfo, cause error) error {
+	logrus.Warnf("Unreadable: %s: %v", dbPath, cause)
+	exec, err := s.batch.executor()
+	if err != nil {
+		return err
+	}
+	err = addScanError(s.ctx, exec, s.run, target.ManagementID, dbPath, cause.Error())
+	if err != nil {
+		return err
+	}
+	err = s.batch.written()
+	if err != nil {
+		return err
+	}
+	if info == nil || info.IsDir() {
+		return nil
+	}
+	f, err := s.lookup(target, dbPath)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if f != nil {
+		return s.batch.exec(scanUpdateStatusSQL, UnreadableStatus, now, f.ID)
+	}
+	mtime := info.ModTime().UTC()
+	return s.batch.exec(scanInsertSQL, target.ManagementID, dbPath, "b", info.Size(), mtime, mtime.UnixNano(), "",
+		UnreadableStatus, now, now)
+}
+
+// underPathClause returns a condition on path matching prefix and the paths
+// under it, or "" for the whole tree. The prefix is matched literally like
+// pathPrefixQueryMod.
+func underPathClause(prefix string) (string, []interface{}) {
+	if prefix == "" || prefix == "." {
+		return "", nil
+	}
+	dir := strings.TrimSuffix(prefix, "/")
+	return "(path = ? OR substr(path, 1, ?) = ?)", []interface{}{dir, utf8.RuneCountInString(dir) + 1, dir + "/"}
+}
+
+// sweep deletes the objects under prefix which were not seen in the walk.
+// Objects imported from manifests are kept since they may not exist
+// locally at all, and so are objects under directories which could not
+// be read or were done before the scan was resumed from resumeAfter.
+func (s *scanner) sweep(target *scanTarget, prefix string, seen map[string]struct{}, skipped []string, resumeAfter string) error {
+	qs := []qm.QueryMod{
+		qm.Select(models.ObjectColumns.ID, models.ObjectColumns.Path),
+		qm.Where(models.ObjectColumns.ManagementID+" = ?", target.ManagementID),
+		qm.And(models.ObjectColumns.Status+" <> ?", ManifestStatus),
+	}
+	if clause, args := underPathClause(prefix); clause != "" {
+		qs = append(qs, qm.And(clause, args...))
+	}
+	fs, err := models.Objects(qs...).All(s.ctx, s.db)
+	if err != nil {
+		return err
+	}
+	for _, f := range fs {
+		if _, ok := seen[f.Path]; ok {
+			continue
+		}
+		if underAny(f.Path, skipped) {
+			continue
+		}
+		if resumeAfter != "" && compareWalkOrder(f.Path, resumeAfter) <= 0 {
+			continue
+		}
+		// logged first so that a deletion is never missed by cscman
+		err = s.batch.exec(scanLogDeletionSQL, s.run.ID, target.ManagementID, f.Path, time.Now())
+		if err != nil {
+			return err
+		}
+		err = s.batch.exec(scanDeleteSQL, f.ID)
+		if err != nil {
+			return err
+		}
+		s.run.Deleted++
+		logrus.Infof("Deleted: %s", f.Path)
+	}
+	return s.batch.commit()
+}
+
+// walk scans the tree at path and deletes the objects of vanished files
+// under it.
+func (s *scanner) walk(target *scanTarget, path string) error {
+	s.walks++
+	resuming := false
+	resumeAfter := ""
+	if s.resume != nil {
+		if s.walks < *s.resume.CheckpointWalk {
+			logrus.Infof("Skipping (done before resume): %s", path)
+			return nil
+		}
+		if s.walks == *s.resume.CheckpointWalk {
+			resuming = true
+			resumeAfter = *s.resume.CheckpointPath
+			logrus.Infof("Resuming after: %s", resumeAfter)
+		}
+	}
+	s.lastWalk = s.walks
+	s.lastPath = resumeAfter
+	prefix, err := target.dbPath(path)
+	if err != nil {
+		return err
+	}
+	// a missing root must not be swept as if all its files were deleted
+	_, err = os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]struct{})
+	skipped := make([]string, 0)
+	err = filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if s.interrupt.Err() != nil {
+			return s.interrupt.Err()
+		}
+		dbPath, dbPathErr := target.dbPath(path)
+		if dbPathErr != nil {
+			return dbPathErr
+		}
+		if resuming && compareWalkOrder(dbPath, resumeAfter) <= 0 {
+			if info != nil && info.IsDir() {
+				if !isAncestor(dbPath, resumeAfter) {
+					return filepath.SkipDir
+				}
+				if err == nil {
+					return s.loadDir(target, dbPath)
+				}
+			}
+			return nil
+		}
+		if err != nil {
+			if os.IsNotExist(err) {
+				// vanished during the scan; swept as deleted
+				return nil
+			}
+			if info == nil || info.IsDir() {
+				skipped = append(skipped, dbPath)
+			} else {
+				seen[dbPath] = struct{}{}
+			}
+			err = s.markUnreadable(target, dbPath, info, err)
+			if err == nil {
+				s.lastPath = dbPath
+			}
+			return err
+		}
+		if info.IsDir() {
+			return s.loadDir(target, dbPath)
+		}
+		if isDBFile(filepath.Base(path)) {
+			return nil
+		}
+		s.run.Seen++
+		seen[dbPath] = struct{}{}
+		err = s.visit(target, path, dbPath, info)
+		if ue, ok := err.(*unreadableError); ok {
+			if os.IsNotExist(ue.error) {
+				delete(seen, dbPath)
+				err = nil
+			} else {
+				err = s.markUnreadable(target, dbPath, info, ue.error)
+			}
+		}
+		if err == nil {
+			s.lastPath = dbPath
+		}
+		return err
+	})
+	s.dirs = nil
+	// commit what is done even on errors so that the counts of the run
+	// stay true and an interrupted run can be resumed from lastPath
+	commitErr := s.batch.commit()
+	if err != nil {
+		return err
+	}
+	if commitErr != nil {
+		return commitErr
+	}
+	err = s.sweep(target, prefix, seen, skipped, resumeAfter)
+	if err != nil {
+		return err
+	}
+	// the errors of earlier runs under the tree were either read again
+	// or recorded again by this walk unless they are under an unreadable
+	// directory
+	exec, err := s.batch.executor()
+	if err != nil {
+		return err
+	}
+	err = resolveScanErrors(s.ctx, exec, s.since, target.ManagementID, prefix, skipped)
+	if err != nil {
+		return err
+	}
+	err = s.batch.written()
+	if err != nil {
+		return err
+	}
+	return s.batch.commit()
+}
+
+// underAny reports whether path is one of dirs or under one of them.
+func underAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if dir == "." || dir == "" || path == dir || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *scanner) scanRoot(m *models.Management, paths []string) error {
+	target := &scanTarget{ManagementID: m.ID.Int64, BasePath: m.BasePath}
+	if len(paths) == 0 {
+		paths = []string{m.BasePath}
+	}
+	start := time.Now()
+	errored := s.run.Errored
+	for _, path := range paths {
+		err := s.walk(target, path)
+		if err != nil {
+			status := ScanRunError
+			if s.interrupt.Err() != nil {
+				status = ScanRunInterrupted
+			}
+			touchManagement(s.ctx, s.db, m, start, status)
+			return err
+		}
+	}
+	if s.run.Errored != errored {
+		return touchManagement(s.ctx, s.db, m, start, ScanRunPartial)
+	}
+	return touchManagement(s.ctx, s.db, m, start, ScanRunOK)
+}
+
+func (s *scanner) scan(args []string) error {
+	if queryRoot != nil {
+		return s.scanRoot(queryRoot, args)
+	}
+	if len(args) == 0 {
+		ms, err := listManagements(s.ctx, s.db)
+		if err != nil {
+			return err
+		}
+		if len(ms) == 0 {
+			logrus.Warn("no roots registered; specify paths to scan or use \"root add\"")
+		}
+		for _, m := range ms {
+			logrus.Infof("Scanning root %s: %s", m.Name, m.BasePath)
+			err = s.scanRoot(m, nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	target := &scanTarget{BasePath: dbRoot, AbsMode: config.AbsMode}
+	for _, arg := range args {
+		err := s.walk(target, arg)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeScan describes what a scan covers for the scan run log.
+func describeScan(ctx context.Context, db *sql.DB, args []string) (string, error) {
+	if queryRoot != nil {
+		if len(args) == 0 {
+			return queryRoot.Name, nil
+		}
+		return queryRoot.Name + ":" + strings.Join(args, ","), nil
+	}
+	if len(args) != 0 {
+		return strings.Join(args, ","), nil
+	}
+	ms, err := listManagements(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, len(ms))
+	for i, m := range ms {
+		names[i] = m.Name
+	}
+	return strings.Join(names, ","), nil
+}
+
+func scan(cmd *cobra.Command, args []string) {
+	ctx, db := prepareDB(true)
+	defer db.Close()
+
+	for _, arg := range args {
+		if strings.HasPrefix(resolveQueryPath(arg), "..") {
+			if queryRoot != nil {
+				logrus.Fatalf("%s is outside of root %s (%s)", arg, queryRoot.Name, queryRoot.BasePath)
+			} else if !config.AbsMode {
+				logrus.Fatalf("%s is outside of %s; use abs mode or --db", arg, dbRoot)
+			}
+		}
+	}
+
+	roots, err := describeScan(ctx, db, args)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	var resume *scanRun
+	if scanResume {
+		resume, err = findResumableScanRun(ctx, db)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if resume.Roots != roots {
+			logrus.Fatalf("scan #%d was of %q; resume it with the same arguments", resume.ID, resume.Roots)
+		}
+	}
+	run, err := createScanRun(ctx, db, roots, resume)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	// the catalog is still written after an interrupt, so keep its
+	// context apart
+	interrupt := interruptContext()
+	s := &scanner{ctx: ctx, db: db, run: run, interrupt: interrupt, resume: resume, since: run.ID}
+	if resume != nil {
+		s.lastWalk, s.lastPath = *resume.CheckpointWalk, *resume.CheckpointPath
+		s.since, err = firstResumedScanRun(ctx, db, resume)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	}
+	s.batch, err = newScanBatch(s.ctx, db, scanBatchSize)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	scanErr := s.scan(args)
+	s.batch.close()
+	status, exitStatus := ScanRunOK, 0
+	if interrupt.Err() != nil {
+		status, exitStatus = ScanRunInterrupted, 130
+		s.checkpoint()
+	} else if scanErr != nil {
+		status, exitStatus = ScanRunError, 1
+	} else if run.Errored != 0 {
+		status, exitStatus = ScanRunPartial, 1
+	}
+	err = finishScanRun(s.ctx, db, run, status, exitStatus)
+	if err != nil {
+		logrus.Error(err)
+	}
+	err = checkpointWAL(s.ctx, db)
+	if err != nil {
+		logrus.Warn(err)
+	}
+	logrus.Infof("Scan #%d: seen=%d hashed=%d inserted=%d updated=%d deleted=%d errored=%d",
+		run.ID, run.Seen, run.Hashed, run.Inserted, run.Updated, run.Deleted, run.Errored)
+	if status == ScanRunInterrupted {
+		logrus.Warnf("Scan #%d interrupted after %s; continue it with \"scan --resume\"", run.ID, s.lastPath)
+		db.Close()
+		os.Exit(exitStatus)
+	}
+	if scanErr != nil {
+		logrus.Fatal(scanErr)
+	}
+	if scanPush != "" {
+		// unreadable files are pushed as such
+		err = pushCatalog(s.ctx, db, scanPush)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	}
+	if run.Errored != 0 {
+		logrus.Errorf("%d paths could not be read; see \"csc errors\"", run.Errored)
+		db.Close()
+		os.Exit(exitStatus)
+	}
+}
+
+const ScanCommandName = "scan"
+
+var ScanCommand = &cobra.Command{
+	Use:  ScanCommandName + " [PATH]...",
+	Args: cobra.ArbitraryArgs,
+	Run:  scan,
+}
+
+var (
+	scanResume    bool
+	scanBatchSize int
+	scanPush      string
+)
+
+func init() {
+	ScanCommand.Flags().BoolVar(&scanResume, "resume", false, "continue the last interrupted scan with the same arguments")
+	ScanCommand.Flags().IntVar(&scanBatchSize, "batch-size", 1000, "number of writes per transaction")
+	ScanCommand.Flags().StringVar(&scanPush, "push", "", "push the catalog to URL of cscman serve after the scan")
+}
@@ -0,0 +1,225 @@
+package csc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/taskie/csc/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+// newTestScanner returns a scanner of a new scan run on db.
+func newTestScanner(t *testing.T, db *sql.DB) *scanner {
+	ctx := context.Background()
+	run, err := createScanRun(ctx, db, "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &scanner{ctx: ctx, db: db, run: run, interrupt: ctx, since: run.ID}
+	s.batch, err = newScanBatch(ctx, db, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.batch.close)
+	return s
+}
+
+func writeTestFiles(t *testing.T, base string, paths ...string) {
+	for _, p := range paths {
+		p = filepath.Join(base, filepath.FromSlash(p))
+		err := os.MkdirAll(filepath.Dir(p), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(p, []byte(p), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func listTestObjectPaths(t *testing.T, db *sql.DB) []string {
+	fs, err := models.Objects(qm.OrderBy(models.ObjectColumns.Path)).All(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make([]string, len(fs))
+	for i, f := range fs {
+		paths[i] = f.Path
+	}
+	return paths
+}
+
+func TestScanSweepsOnlyUnderPath(t *testing.T) {
+	base := t.TempDir()
+	writeTestFiles(t, base, "a_b/x")
+	// vanished files; only a_b/gone is under the scanned directory
+	db := openTestDB(t, map[string]int64{"a_b/gone": 1, "axb/y": 1, "A_B/z": 1, "a_b2/w": 1, "a_b%/v": 1})
+	s := newTestScanner(t, db)
+	err := s.walk(&scanTarget{BasePath: base}, filepath.Join(base, "a_b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"A_B/z", "a_b%/v", "a_b/x", "a_b2/w", "axb/y"}
+	if got := listTestObjectPaths(t, db); !reflect.DeepEqual(got, want) {
+		t.Errorf("objects after a scan of a_b: %q, want %q", got, want)
+	}
+	if s.run.Deleted != 1 {
+		t.Errorf("deleted %d objects, want 1", s.run.Deleted)
+	}
+}
+
+func listTestErrorPaths(t *testing.T, db *sql.DB) []string {
+	es, err := listUnresolvedErrors(context.Background(), db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make([]string, len(es))
+	for i, e := range es {
+		paths[i] = e.Path
+	}
+	return paths
+}
+
+func TestScanResolvesErrors(t *testing.T) {
+	base := t.TempDir()
+	writeTestFiles(t, base, "a/x", "a/y", "b/z")
+	db := openTestDB(t, nil)
+	target := &scanTarget{BasePath: base}
+
+	s := newTestScanner(t, db)
+	info, err := os.Stat(filepath.Join(base, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.markUnreadable(target, "a", info, errors.New("permission denied"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"a/y", "b/z", "gone"} {
+		info, err = os.Stat(filepath.Join(base, "a", "x"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.markUnreadable(target, p, info, errors.New("input/output error"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = s.batch.commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the directory is listed though it has no object
+	want := []string{"a", "a/y", "b/z", "gone"}
+	if got := listTestErrorPaths(t, db); !reflect.DeepEqual(got, want) {
+		t.Errorf("errors: %q, want %q", got, want)
+	}
+
+	// a later scan of a resolves the errors under it
+	s = newTestScanner(t, db)
+	err = s.walk(target, filepath.Join(base, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"b/z", "gone"}
+	if got := listTestErrorPaths(t, db); !reflect.DeepEqual(got, want) {
+		t.Errorf("errors after a scan of a: %q, want %q", got, want)
+	}
+	s = newTestScanner(t, db)
+	err = s.walk(target, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := listTestErrorPaths(t, db); len(got) != 0 {
+		t.Errorf("errors after a scan of all: %q", got)
+	}
+}
+
+func TestCompareWalkOrder(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"a", "a", 0},
+		{".", "a", -1},
+		{"a", ".", 1},
+		{"a", "a/b", -1},
+		{"a/b", "a", 1},
+		{"a/b", "a/c", -1},
+		{"a/z", "a-b", -1},
+		{"a-b", "a/z", 1},
+		{"a/z", "a.b", -1},
+		{"a.b/c", "a/b/c", 1},
+		{"a/b/c", "a/b-c", -1},
+		{"a/b/c", "a/b.c", -1},
+		{"b", "a/z/z", 1},
+	}
+	for _, c := range cases {
+		if got := compareWalkOrder(c.a, c.b); got != c.want {
+			t.Errorf("compareWalkOrder(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestCompareWalkOrderMatchesWalk(t *testing.T) {
+	base := t.TempDir()
+	writeTestFiles(t, base, "a/z", "a-b", "a.c/x", "a/b/c", "a/b-c", "a/b.c/d", "a0")
+	target := &scanTarget{BasePath: base}
+	paths := make([]string, 0)
+	err := filepath.Walk(base, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		dbPath, err := target.dbPath(path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, dbPath)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range paths {
+		for j := range paths {
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := compareWalkOrder(paths[i], paths[j]); got != want {
+				t.Errorf("compareWalkOrder(%q, %q) = %d, want %d", paths[i], paths[j], got, want)
+			}
+		}
+	}
+}
+
+func TestIsAncestor(t *testing.T) {
+	cases := []struct {
+		dir, path string
+		want      bool
+	}{
+		{".", "a", true},
+		{"a", "a/b", true},
+		{"a/", "a/b", true},
+		{"a", "a/b/c", true},
+		{"a", "a", false},
+		{"a", "a-b", false},
+		{"a", "a.b/c", false},
+		{"a", "ab/c", false},
+		{"a/b", "a", false},
+	}
+	for _, c := range cases {
+		if got := isAncestor(c.dir, c.path); got != c.want {
+			t.Errorf("isAncestor(%q, %q) = %v, want %v", c.dir, c.path, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package csc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/taskie/csc/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+type statsBucket struct {
+	Label string `json:"label"`
+	Count int64  `json:"count"`
+	Bytes int64  `json:"bytes"`
+}
+
+type statsFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+type statsDuplicate struct {
+	Sha256      string `json:"sha256"`
+	Size        int64  `json:"size"`
+	Count       int64  `json:"count"`
+	WastedBytes int64  `json:"wasted_bytes"`
+}
+
+type catalogStats struct {
+	GeneratedAt  time.Time         `json:"generated_at"`
+	TotalFiles   int64             `json:"total_files"`
+	TotalBytes   int64             `json:"total_bytes"`
+	UniqueFiles  int64             `json:"unique_files"`
+	UniqueBytes  int64             `json:"unique_bytes"`
+	DedupRatio   float64           `json:"dedup_ratio"`
+	Extensions   []*statsBucket    `json:"extensions"`
+	SizeBuckets  []*statsBucket    `json:"size_buckets"`
+	AgeBuckets   []*statsBucket    `json:"age_buckets"`
+	Statuses     []*statsBucket    `json:"statuses"`
+	LargestFiles []*statsFile      `json:"largest_files"`
+	Duplicates   []*statsDuplicate `json:"duplicates"`
+}
+
+var sizeBucketBounds = []struct {
+	label string
+	limit int64
+}{
+	{"< 1 KiB", 1 << 10},
+	{"< 64 KiB", 64 << 10},
+	{"< 1 MiB", 1 << 20},
+	{"< 16 MiB", 16 << 20},
+	{"< 256 MiB", 256 << 20},
+	{"< 1 GiB", 1 << 30},
+	{"< 16 GiB", 16 << 30},
+	{">= 16 GiB", -1},
+}
+
+var ageBucketBounds = []struct {
+	label string
+	limit time.Duration
+}{
+	{"< 1 day", 24 * time.Hour},
+	{"< 1 week", 7 * 24 * time.Hour},
+	{"< 1 month", 30 * 24 * time.Hour},
+	{"< 1 year", 365 * 24 * time.Hour},
+	{"< 5 years", 5 * 365 * 24 * time.Hour},
+	{">= 5 years", -1},
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func extensionOf(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return "(none)"
+	}
+	return ext
+}
+
+func addToBucket(bucketMap map[string]*statsBucket, label string, size int64) {
+	b, ok := bucketMap[label]
+	if !ok {
+		b = &statsBucket{Label: label}
+		bucketMap[label] = b
+	}
+	b.Count++
+	b.Bytes += size
+}
+
+func sortedBuckets(bucketMap map[string]*statsBucket, top int) []*statsBucket {
+	bs := make([]*statsBucket, 0, len(bucketMap))
+	for _, b := range bucketMap {
+		bs = append(bs, b)
+	}
+	sort.Slice(bs, func(i, j int) bool {
+		if bs[i].Bytes != bs[j].Bytes {
+			return bs[i].Bytes > bs[j].Bytes
+		}
+		return bs[i].Label < bs[j].Label
+	})
+	if top >= 0 && len(bs) > top {
+		bs = bs[:top]
+	}
+	return bs
+}
+
+func orderedBuckets(bucketMap map[string]*statsBucket, labels []string) []*statsBucket {
+	bs := make([]*statsBucket, 0, len(labels))
+	for _, label := range labels {
+		if b, ok := bucketMap[label]; ok {
+			bs = append(bs, b)
+		} else {
+			bs = append(bs, &statsBucket{Label: label})
+		}
+	}
+	return bs
+}
+
+// calcStats summarizes all objects under prefix in a single pass. The ranked
+// sections have up to top entries each.
+func calcStats(ctx context.Context, db *sql.DB, prefix string, top int) (*catalogStats, error) {
+	if top < 0 {
+		return nil, fmt.Errorf("top must not be negative: %d", top)
+	}
+	rows, err := models.Objects(append(rootQueryMods(),
+		qm.Select(
+			models.ObjectColumns.Path,
+			models.ObjectColumns.Size,
+			models.ObjectColumns.Mtime,
+			models.ObjectColumns.Sha256,
+			models.ObjectColumns.Status),
+		pathPrefixQueryMod(prefix))...).QueryContext(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	st := &catalogStats{GeneratedAt: now}
+	extMap := make(map[string]*statsBucket)
+	sizeMap := make(map[string]*statsBucket)
+	ageMap := make(map[string]*statsBucket)
+	statusMap := make(map[string]*statsBucket)
+	dupMap := make(map[string]*statsDuplicate)
+	largest := make([]*statsFile, 0, top+1)
+
+	for rows.Next() {
+		var path, sha256Hex, status string
+		var size int64
+		var mtime time.Time
+		err = rows.Scan(&path, &size, &mtime, &sha256Hex, &status)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			size = 0
+		}
+		st.TotalFiles++
+		st.TotalBytes += size
+		addToBucket(extMap, extensionOf(path), size)
+		addToBucket(statusMap, status, size)
+		for _, sb := range sizeBucketBounds {
+			if sb.limit < 0 || size < sb.limit {
+				addToBucket(sizeMap, sb.label, size)
+				break
+			}
+		}
+		age := now.Sub(mtime)
+		for _, ab := range ageBucketBounds {
+			if ab.limit < 0 || age < ab.limit {
+				addToBucket(ageMap, ab.label, size)
+				break
+			}
+		}
+		if sha256Hex != "" {
+			d, ok := dupMap[sha256Hex]
+			if !ok {
+				d = &statsDuplicate{Sha256: sha256Hex, Size: size}
+				dupMap[sha256Hex] = d
+				st.UniqueFiles++
+				st.UniqueBytes += size
+			}
+			d.Count++
+		}
+		if top > 0 && (len(largest) < top || largest[len(largest)-1].Size < size) {
+			i := sort.Search(len(largest), func(i int) bool { return largest[i].Size < size })
+			largest = append(largest, nil)
+			copy(largest[i+1:], largest[i:])
+			largest[i] = &statsFile{Path: path, Size: size, Sha256: sha256Hex}
+			if len(largest) > top {
+				largest = largest[:top]
+			}
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if st.UniqueBytes > 0 {
+		st.DedupRatio = float64(st.TotalBytes) / float64(st.UniqueBytes)
+	}
+	st.Extensions = sortedBuckets(extMap, top)
+	st.Statuses = sortedBuckets(statusMap, -1)
+	sizeLabels := make([]string, len(sizeBucketBounds))
+	for i, sb := range sizeBucketBounds {
+		sizeLabels[i] = sb.label
+	}
+	st.SizeBuckets = orderedBuckets(sizeMap, sizeLabels)
+	ageLabels := make([]string, len(ageBucketBounds))
+	for i, ab := range ageBucketBounds {
+		ageLabels[i] = ab.label
+	}
+	st.AgeBuckets = orderedBuckets(ageMap, ageLabels)
+	st.LargestFiles = largest
+
+	dups := make([]*statsDuplicate, 0)
+	for _, d := range dupMap {
+		if d.Count > 1 {
+			d.WastedBytes = d.Size * (d.Count - 1)
+			dups = append(dups, d)
+		}
+	}
+	sort.Slice(dups, func(i, j int) bool {
+		if dups[i].Count != dups[j].Count {
+			return dups[i].Count > dups[j].Count
+		}
+		if dups[i].WastedBytes != dups[j].WastedBytes {
+			return dups[i].WastedBytes > dups[j].WastedBytes
+		}
+		return dups[i].Sha256 < dups[j].Sha256
+	})
+	if len(dups) > top {
+		dups = dups[:top]
+	}
+	st.Duplicates = dups
+	return st, nil
+}
+
+func printBuckets(title string, bs []*statsBucket) {
+	fmt.Printf("\n%s:\n", title)
+	for _, b := range bs {
+		fmt.Printf("  %-12s\t%10d files\t%12s\n", b.Label, b.Count, formatSize(b.Bytes))
+	}
+}
+
+func printStats(st *catalogStats) {
+	fmt.Printf("Total files:  %d\n", st.TotalFiles)
+	fmt.Printf("Total bytes:  %d (%s)\n", st.TotalBytes, formatSize(st.TotalBytes))
+	fmt.Printf("Unique files: %d\n", st.UniqueFiles)
+	fmt.Printf("Unique bytes: %d (%s)\n", st.UniqueBytes, formatSize(st.UniqueBytes))
+	fmt.Printf("Dedup ratio:  %.3f\n", st.DedupRatio)
+	printBuckets("Statuses", st.Statuses)
+	printBuckets("Extensions", st.Extensions)
+	printBuckets("Sizes", st.SizeBuckets)
+	printBuckets("Ages (mtime)", st.AgeBuckets)
+	fmt.Printf("\nLargest files:\n")
+	for _, f := range st.LargestFiles {
+		fmt.Printf("  %12s\t%s\n", formatSize(f.Size), f.Path)
+	}
+	fmt.Printf("\nMost duplicated:\n")
+	for _, d := range st.Duplicates {
+		fmt.Printf("  %s\t%6d copies\t%12s wasted\n", d.Sha256, d.Count, formatSize(d.WastedBytes))
+	}
+}
+
+var (
+	statsJSON bool
+	statsTop  int
+)
+
+func stats(cmd *cobra.Command, args []string) {
+	ctx, db := prepare()
+	defer db.Close()
+
+	prefix := prefixFromArgs(args)
+	st, err := calcStats(ctx, db, prefix, statsTop)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if statsJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		err = enc.Encode(st)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		return
+	}
+	printStats(st)
+}
+
+const StatsCommandName = "stats"
+
+var StatsCommand = &cobra.Command{
+	Use:  StatsCommandName + " [PREFIX]",
+	Args: cobra.MaximumNArgs(1),
+	Run:  stats,
+}
+
+func init() {
+	StatsCommand.Flags().BoolVarP(&statsJSON, "json", "j", false, "output as JSON")
+	StatsCommand.Flags().IntVarP(&statsTop, "top", "n", 10, "number of entries in ranked sections (0 to omit them)")
+}
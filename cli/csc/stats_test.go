@@ -0,0 +1,27 @@
+package csc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCalcStatsTop(t *testing.T) {
+	db := openTestDB(t, map[string]int64{"a.txt": 1, "b.jpg": 2, "c.png": 4})
+	for _, top := range []int{0, 2, 5} {
+		st, err := calcStats(context.Background(), db, "", top)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := top
+		if want > 3 {
+			want = 3
+		}
+		if len(st.LargestFiles) != want || len(st.Extensions) != want {
+			t.Errorf("top %d: %d largest files and %d extensions, want %d", top, len(st.LargestFiles), len(st.Extensions), want)
+		}
+	}
+	_, err := calcStats(context.Background(), db, "", -5)
+	if err == nil {
+		t.Error("negative top is accepted")
+	}
+}
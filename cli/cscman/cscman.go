@@ -3,7 +3,12 @@ package cscman
 import (
 	"context"
 	"fmt"
+	"os"
+	"path"
 	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
 
 	"github.com/iancoleman/strcase"
 	"github.com/k0kubun/pp"
@@ -12,6 +17,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/taskie/csc"
 	"github.com/taskie/csc/cscman"
+	"github.com/taskie/csc/cscman/models"
 	"github.com/taskie/osplus"
 	"github.com/volatiletech/sqlboiler/boil"
 )
@@ -44,6 +50,10 @@ func prepare() (context.Context, *cscman.CscMan) {
 	return ctx, cm
 }
 
+func printSyncResult(name string, result *cscman.SyncResult) {
+	fmt.Printf("%s\tinserted=%d updated=%d deleted=%d\n", name, result.Inserted, result.Updated, result.Deleted)
+}
+
 func register(cmd *cobra.Command, args []string) {
 	ctx, cm := prepare()
 	defer cm.Close()
@@ -52,12 +62,15 @@ func register(cmd *cobra.Command, args []string) {
 	if len(args) > 1 {
 		url = args[1]
 	}
-	err := cm.RegisterNamespace(ctx, name, url)
+	result, err := cm.RegisterNamespace(ctx, name, url, registerType)
 	if err != nil {
 		logrus.Fatal(err)
 	}
+	printSyncResult(name, result)
 }
 
+var registerType string
+
 const RegisterCommandName = "register"
 
 var RegisterCommand = &cobra.Command{
@@ -66,25 +79,99 @@ var RegisterCommand = &cobra.Command{
 	Run:  register,
 }
 
+// matchNamespaces selects the namespaces whose names match any of patterns,
+// which are names or globs. It also returns the patterns matching none.
+func matchNamespaces(namespaces []*models.Namespace, patterns []string) ([]*models.Namespace, []string, error) {
+	matched := make([]*models.Namespace, 0)
+	hits := make([]bool, len(patterns))
+	for _, namespace := range namespaces {
+		ok := false
+		for i, pattern := range patterns {
+			m, err := path.Match(pattern, namespace.Name)
+			if err != nil {
+				return nil, nil, err
+			}
+			if m {
+				hits[i] = true
+				ok = true
+			}
+		}
+		if ok {
+			matched = append(matched, namespace)
+		}
+	}
+	missing := make([]string, 0)
+	for i, pattern := range patterns {
+		if !hits[i] {
+			missing = append(missing, pattern)
+		}
+	}
+	return matched, missing, nil
+}
+
 func sync(cmd *cobra.Command, args []string) {
 	ctx, cm := prepare()
 	defer cm.Close()
-	name := args[0]
-	namespace, err := cm.FindNamespace(ctx, name)
+	if syncAll == (len(args) != 0) {
+		logrus.Fatal("specify namespaces or --all")
+	}
+	namespaces, err := cm.ListNamespaces(ctx)
 	if err != nil {
 		logrus.Fatal(err)
 	}
-	err = cm.SyncWithCSCDB(ctx, namespace)
+	missing := []string{}
+	if syncAll {
+		// the hosts of push namespaces sync them
+		pulled := make([]*models.Namespace, 0, len(namespaces))
+		for _, namespace := range namespaces {
+			if namespace.Type != cscman.TransportTypePush {
+				pulled = append(pulled, namespace)
+			}
+		}
+		namespaces = pulled
+	} else {
+		namespaces, missing, err = matchNamespaces(namespaces, args)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	}
+	results, err := cm.SyncNamespaces(ctx, namespaces, syncJobs, syncFull)
 	if err != nil {
 		logrus.Fatal(err)
 	}
+	for _, name := range missing {
+		results = append(results, &cscman.NamespaceSyncResult{Name: name, Err: fmt.Errorf("no such namespace")})
+	}
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATUS\tINSERTED\tUPDATED\tDELETED\tERROR")
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(w, "%s\terror\t-\t-\t-\t%v\n", r.Name, r.Err)
+			continue
+		}
+		fmt.Fprintf(w, "%s\tok\t%d\t%d\t%d\t\n", r.Name, r.Result.Inserted, r.Result.Updated, r.Result.Deleted)
+	}
+	w.Flush()
+	if failed != 0 {
+		cm.Close()
+		os.Exit(1)
+	}
 }
 
+var (
+	syncFull bool
+	syncAll  bool
+	syncJobs int
+)
+
 const SyncCommandName = "sync"
 
 var SyncCommand = &cobra.Command{
-	Use:  SyncCommandName + " NAME",
-	Args: cobra.ExactArgs(1),
+	Use:  SyncCommandName + " [NAME|GLOB...]",
+	Args: cobra.ArbitraryArgs,
 	Run:  sync,
 }
 
@@ -93,7 +180,7 @@ func sha256(cmd *cobra.Command, args []string) {
 	defer cm.Close()
 
 	for _, arg := range args {
-		objs, err := cm.FindObjectBySha256Prefix(ctx, arg)
+		objs, err := cm.FindObjectBySha256Prefix(ctx, arg, nil, 0)
 		if err != nil {
 			logrus.Fatal(err)
 		}
@@ -124,7 +211,7 @@ func find(cmd *cobra.Command, args []string) {
 		}
 		sha256hexs = append(sha256hexs, sha256hex)
 	}
-	objs, err := cm.FindObjectBySha256s(ctx, args)
+	objs, err := cm.FindObjectBySha256s(ctx, args, nil, 0)
 	if err != nil {
 		logrus.Fatal(err)
 	}
@@ -141,6 +228,78 @@ var FindCommand = &cobra.Command{
 	Run:  find,
 }
 
+func migrateUp(cmd *cobra.Command, args []string) {
+	ctx, cm := prepare()
+	defer cm.Close()
+	applied, err := cm.MigrateUp(ctx)
+	for _, mig := range applied {
+		fmt.Printf("Applied %02d-%s\n", mig.Version, mig.Name)
+	}
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+func migrateDown(cmd *cobra.Command, args []string) {
+	ctx, cm := prepare()
+	defer cm.Close()
+	steps := 1
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		steps = n
+	}
+	reverted, err := cm.MigrateDown(ctx, steps)
+	for _, mig := range reverted {
+		fmt.Printf("Reverted %02d-%s\n", mig.Version, mig.Name)
+	}
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+func migrateStatus(cmd *cobra.Command, args []string) {
+	ctx, cm := prepare()
+	defer cm.Close()
+	sts, err := cm.MigrationStatus(ctx)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	for _, st := range sts {
+		appliedAt := "pending"
+		if st.AppliedAt != nil {
+			appliedAt = st.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%02d\t%s\t%s\n", st.Version, st.Name, appliedAt)
+	}
+}
+
+const MigrateCommandName = "migrate"
+
+var MigrateCommand = &cobra.Command{
+	Use: MigrateCommandName,
+}
+
+var MigrateUpCommand = &cobra.Command{
+	Use:  "up",
+	Args: cobra.NoArgs,
+	Run:  migrateUp,
+}
+
+var MigrateDownCommand = &cobra.Command{
+	Use:  "down [STEPS]",
+	Args: cobra.MaximumNArgs(1),
+	Run:  migrateDown,
+}
+
+var MigrateStatusCommand = &cobra.Command{
+	Use:  "status",
+	Args: cobra.NoArgs,
+	Run:  migrateStatus,
+}
+
 const CommandName = "cscman"
 
 var Command = &cobra.Command{
@@ -148,7 +307,12 @@ var Command = &cobra.Command{
 }
 
 func init() {
-	Command.AddCommand(RegisterCommand, SyncCommand, Sha256Command, FindCommand)
+	MigrateCommand.AddCommand(MigrateUpCommand, MigrateDownCommand, MigrateStatusCommand)
+	RegisterCommand.Flags().StringVarP(&registerType, "type", "t", "", "transport type: local, rsync, http or push (default: detected from URL)")
+	SyncCommand.Flags().BoolVar(&syncFull, "full", false, "compare all objects instead of the changes since the last sync")
+	SyncCommand.Flags().BoolVarP(&syncAll, "all", "a", false, "sync all namespaces")
+	SyncCommand.Flags().IntVarP(&syncJobs, "jobs", "j", 4, "number of namespaces synced at a time")
+	Command.AddCommand(RegisterCommand, SyncCommand, NsCommand, Sha256Command, FindCommand, ServeCommand, TokenCommand, MigrateCommand)
 	Command.PersistentFlags().StringVarP(&configFile, "config", "c", "", `config file (default "`+CommandName+`.yml")`)
 	Command.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 	Command.PersistentFlags().BoolVar(&debug, "debug", false, "debug output")
@@ -0,0 +1,146 @@
+package cscman
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// formatSyncedAt formats the time of the last sync, which is the epoch if
+// the namespace has never been synced.
+func formatSyncedAt(t time.Time) string {
+	if t.Unix() <= 0 {
+		return "-"
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func nsList(cmd *cobra.Command, args []string) {
+	ctx, cm := prepare()
+	defer cm.Close()
+
+	sts, err := cm.ListNamespaceStats(ctx)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	for _, st := range sts {
+		fmt.Printf("%s\t%s\t%s\t%s\t%d\t%d\t%s\t%s\n", st.Name, st.URL, st.Type, st.Status, st.Objects, st.Bytes,
+			st.CSCDBSha256, formatSyncedAt(st.SyncedAt))
+	}
+}
+
+func nsShow(cmd *cobra.Command, args []string) {
+	ctx, cm := prepare()
+	defer cm.Close()
+
+	st, err := cm.GetNamespaceStats(ctx, args[0])
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	fmt.Printf("name\t%s\n", st.Name)
+	fmt.Printf("url\t%s\n", st.URL)
+	fmt.Printf("type\t%s\n", st.Type)
+	fmt.Printf("status\t%s\n", st.Status)
+	fmt.Printf("last_error\t%s\n", st.LastError)
+	fmt.Printf("description\t%s\n", st.Description)
+	fmt.Printf("objects\t%d\n", st.Objects)
+	fmt.Printf("bytes\t%d\n", st.Bytes)
+	fmt.Printf("csc_db_size\t%d\n", st.CSCDBSize)
+	fmt.Printf("csc_db_mtime\t%s\n", st.CSCDBMtime.Local().Format(time.RFC3339))
+	fmt.Printf("csc_db_sha256\t%s\n", st.CSCDBSha256)
+	fmt.Printf("synced_at\t%s\n", formatSyncedAt(st.SyncedAt))
+	fmt.Printf("created_at\t%s\n", st.CreatedAt.Local().Format(time.RFC3339))
+	fmt.Printf("updated_at\t%s\n", st.UpdatedAt.Local().Format(time.RFC3339))
+}
+
+func nsSetURL(cmd *cobra.Command, args []string) {
+	ctx, cm := prepare()
+	defer cm.Close()
+
+	err := cm.SetNamespaceURL(ctx, args[0], args[1], nsSetURLType)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+var nsSetURLType string
+
+func nsSetDescription(cmd *cobra.Command, args []string) {
+	ctx, cm := prepare()
+	defer cm.Close()
+
+	err := cm.SetNamespaceDescription(ctx, args[0], args[1])
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+func nsRename(cmd *cobra.Command, args []string) {
+	ctx, cm := prepare()
+	defer cm.Close()
+
+	err := cm.RenameNamespace(ctx, args[0], args[1])
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+func nsRemove(cmd *cobra.Command, args []string) {
+	ctx, cm := prepare()
+	defer cm.Close()
+
+	n, err := cm.RemoveNamespace(ctx, args[0])
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	logrus.Infof("Removed namespace %s with %d objects", args[0], n)
+}
+
+const NsCommandName = "ns"
+
+var NsCommand = &cobra.Command{
+	Use: NsCommandName,
+}
+
+var NsListCommand = &cobra.Command{
+	Use:  "list",
+	Args: cobra.NoArgs,
+	Run:  nsList,
+}
+
+var NsShowCommand = &cobra.Command{
+	Use:  "show NAME",
+	Args: cobra.ExactArgs(1),
+	Run:  nsShow,
+}
+
+var NsSetURLCommand = &cobra.Command{
+	Use:  "set-url NAME URL",
+	Args: cobra.ExactArgs(2),
+	Run:  nsSetURL,
+}
+
+var NsSetDescriptionCommand = &cobra.Command{
+	Use:  "set-description NAME DESCRIPTION",
+	Args: cobra.ExactArgs(2),
+	Run:  nsSetDescription,
+}
+
+var NsRenameCommand = &cobra.Command{
+	Use:  "rename NAME NEW_NAME",
+	Args: cobra.ExactArgs(2),
+	Run:  nsRename,
+}
+
+var NsRemoveCommand = &cobra.Command{
+	Use:  "remove NAME",
+	Args: cobra.ExactArgs(1),
+	Run:  nsRemove,
+}
+
+func init() {
+	NsSetURLCommand.Flags().StringVarP(&nsSetURLType, "type", "t", "", "transport type: local, rsync, http or push (default: detected from URL)")
+	NsCommand.AddCommand(NsListCommand, NsShowCommand, NsSetURLCommand, NsSetDescriptionCommand, NsRenameCommand, NsRemoveCommand)
+}
@@ -0,0 +1,44 @@
+package cscman
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/taskie/csc/cscman"
+)
+
+func serve(cmd *cobra.Command, args []string) {
+	ctx, cm := prepare()
+	defer cm.Close()
+
+	err := cm.CheckSchema(ctx)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	srv := &http.Server{
+		Addr:              serveListen,
+		Handler:           cscman.NewServer(cm),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	logrus.Infof("Listening on %s", serveListen)
+	err = srv.ListenAndServe()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+var serveListen string
+
+const ServeCommandName = "serve"
+
+var ServeCommand = &cobra.Command{
+	Use:  ServeCommandName,
+	Args: cobra.NoArgs,
+	Run:  serve,
+}
+
+func init() {
+	ServeCommand.Flags().StringVarP(&serveListen, "listen", "l", "localhost:8080", "address to listen on")
+}
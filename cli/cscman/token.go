@@ -0,0 +1,80 @@
+package cscman
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func tokenCreate(cmd *cobra.Command, args []string) {
+	ctx, cm := prepare()
+	defer cm.Close()
+
+	secret, err := cm.CreateToken(ctx, args[0], tokenCreateScopes, tokenCreateNamespaces)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	logrus.Infof("Created token %s; it can't be shown again", args[0])
+	fmt.Println(secret)
+}
+
+var (
+	tokenCreateScopes     []string
+	tokenCreateNamespaces []string
+)
+
+func tokenList(cmd *cobra.Command, args []string) {
+	ctx, cm := prepare()
+	defer cm.Close()
+
+	tokens, err := cm.ListTokens(ctx)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	for _, token := range tokens {
+		fmt.Printf("%s\t%s\t%s\t%s\n", token.Name, token.Scopes, token.Namespaces, token.CreatedAt.Local().Format(time.RFC3339))
+	}
+}
+
+func tokenRevoke(cmd *cobra.Command, args []string) {
+	ctx, cm := prepare()
+	defer cm.Close()
+
+	err := cm.RevokeToken(ctx, args[0])
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+const TokenCommandName = "token"
+
+var TokenCommand = &cobra.Command{
+	Use: TokenCommandName,
+}
+
+var TokenCreateCommand = &cobra.Command{
+	Use:  "create NAME",
+	Args: cobra.ExactArgs(1),
+	Run:  tokenCreate,
+}
+
+var TokenListCommand = &cobra.Command{
+	Use:  "list",
+	Args: cobra.NoArgs,
+	Run:  tokenList,
+}
+
+var TokenRevokeCommand = &cobra.Command{
+	Use:  "revoke NAME",
+	Args: cobra.ExactArgs(1),
+	Run:  tokenRevoke,
+}
+
+func init() {
+	TokenCreateCommand.Flags().StringSliceVarP(&tokenCreateScopes, "scope", "s", []string{"read"}, "scopes: read and/or push")
+	TokenCreateCommand.Flags().StringSliceVarP(&tokenCreateNamespaces, "namespace", "n", nil, "namespaces or globs which the scopes apply to (\"*\" for all)")
+	TokenCreateCommand.MarkFlagRequired("namespace")
+	TokenCommand.AddCommand(TokenCreateCommand, TokenListCommand, TokenRevokeCommand)
+}
@@ -0,0 +1,150 @@
+package cscman
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/taskie/csc"
+)
+
+// testMySQLDriver is SQLite which takes the MySQL statements of cscman.
+type testMySQLDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func init() {
+	sql.Register("sqlite3_mysql", &testMySQLDriver{})
+}
+
+var testMySQLRewrites = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// only objects are upserted
+	{regexp.MustCompile(`ON DUPLICATE KEY UPDATE `), "ON CONFLICT (`namespace`, `path`) DO UPDATE SET "},
+	{regexp.MustCompile("VALUES\\((`\\w+`)\\)"), "excluded.$1"},
+	{regexp.MustCompile(` FOR UPDATE`), ""},
+	{regexp.MustCompile(`information_schema\.tables WHERE table_schema = DATABASE\(\) AND table_name =`),
+		"sqlite_master WHERE type = 'table' AND name ="},
+}
+
+func rewriteTestMySQL(query string) string {
+	for _, r := range testMySQLRewrites {
+		query = r.re.ReplaceAllString(query, r.repl)
+	}
+	return query
+}
+
+func (d *testMySQLDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &testMySQLConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type testMySQLConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c *testMySQLConn) Prepare(query string) (driver.Stmt, error) {
+	return c.SQLiteConn.Prepare(rewriteTestMySQL(query))
+}
+
+func (c *testMySQLConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.SQLiteConn.PrepareContext(ctx, rewriteTestMySQL(query))
+}
+
+func (c *testMySQLConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.SQLiteConn.ExecContext(ctx, rewriteTestMySQL(query), args)
+}
+
+func (c *testMySQLConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.SQLiteConn.QueryContext(ctx, rewriteTestMySQL(query), args)
+}
+
+// testCentralSchema is the schema of the cscman database in SQLite. Its
+// paths compare bytes like the utf8mb4_0900_bin collation of MySQL.
+var testCentralSchema = []string{
+	`CREATE TABLE namespaces (
+    name TEXT NOT NULL PRIMARY KEY,
+    url TEXT NOT NULL,
+    type TEXT NOT NULL,
+    csc_db_size INTEGER NOT NULL,
+    csc_db_mtime DATETIME NOT NULL,
+    csc_db_sha256 TEXT NOT NULL,
+    etag TEXT NOT NULL DEFAULT '',
+    synced_updated_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00',
+    synced_scan_run_id INTEGER NOT NULL DEFAULT 0,
+    synced_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00',
+    status TEXT NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+)`,
+	`CREATE TABLE objects (
+    id INTEGER PRIMARY KEY,
+    namespace TEXT NOT NULL,
+    path TEXT NOT NULL,
+    type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    mtime DATETIME NOT NULL,
+    mtime_ns INTEGER NOT NULL DEFAULT 0,
+    sha256 TEXT NOT NULL,
+    status TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE (namespace, path)
+)`,
+	`CREATE TABLE tokens (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    token_sha256 TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    namespaces TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+)`,
+}
+
+// openTestCentralDB opens an empty database for cscman on SQLite.
+func openTestCentralDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3_mysql", filepath.Join(t.TempDir(), "cscman.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func execTestStatements(t *testing.T, db *sql.DB, stmts []string) {
+	for _, stmt := range stmts {
+		_, err := db.Exec(stmt)
+		if err != nil {
+			t.Fatalf("%s: %v", strings.SplitN(stmt, "\n", 2)[0], err)
+		}
+	}
+}
+
+// newTestCscMan returns a CscMan on a SQLite database with the schema of
+// all the migrations.
+func newTestCscMan(t *testing.T) *CscMan {
+	db := openTestCentralDB(t)
+	execTestStatements(t, db, testCentralSchema)
+	ms, err := csc.CscManMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = csc.NewMigrator(db, ms).MarkApplied(context.Background(), ms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &CscMan{config: &CscManConfig{}, db: db}
+}
@@ -0,0 +1,153 @@
+package cscman
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/taskie/csc/cscman/models"
+	cscModels "github.com/taskie/csc/models"
+	"github.com/volatiletech/sqlboiler/boil"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+// centralPageSize is the number of central objects read by a query.
+var centralPageSize = 1000
+
+func sqliteTableExists(ctx context.Context, db boil.ContextExecutor, table string) (bool, error) {
+	var n int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&n)
+	return n != 0, err
+}
+
+func sqliteColumnExists(ctx context.Context, db boil.ContextExecutor, table string, column string) (bool, error) {
+	var n int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&n)
+	return n != 0, err
+}
+
+// centralPathSQL is the central path of a row of alias joined with its root
+// as m. Objects under named roots are stored with the base path of their root
+// so that objects of different roots don't collide.
+func centralPathSQL(alias string) string {
+	return fmt.Sprintf("CASE WHEN %[1]s.management_id = 0 THEN %[1]s.path ELSE rtrim(ifnull(m.base_path, ''), '/') || '/' || %[1]s.path END", alias)
+}
+
+// sourceQuery builds the query of the objects of a csc.db ordered by their
+// central paths, filtered by where if it is not empty. csc.db files of older
+// versions lack roots and mtime_ns.
+func sourceQuery(ctx context.Context, db boil.ContextExecutor, where string) (string, error) {
+	hasRoots, err := sqliteColumnExists(ctx, db, "objects", "management_id")
+	if err != nil {
+		return "", err
+	}
+	if hasRoots {
+		hasRoots, err = sqliteTableExists(ctx, db, "managements")
+		if err != nil {
+			return "", err
+		}
+	}
+	hasMtimeNs, err := sqliteColumnExists(ctx, db, "objects", "mtime_ns")
+	if err != nil {
+		return "", err
+	}
+	mtimeNs := "0"
+	if hasMtimeNs {
+		mtimeNs = "o.mtime_ns"
+	}
+	if where != "" {
+		where = " WHERE " + where
+	}
+	if !hasRoots {
+		return "SELECT o.path, o.type, o.size, o.mtime, " + mtimeNs + ", o.sha256, o.status FROM objects o" + where + " ORDER BY o.path", nil
+	}
+	return "SELECT " + centralPathSQL("o") + " AS central_path, o.type, o.size, o.mtime, " + mtimeNs + ", o.sha256, o.status" +
+		" FROM objects o LEFT JOIN managements m ON m.id = o.management_id" + where + " ORDER BY central_path", nil
+}
+
+// sourceCursor streams the objects of a csc.db ordered by central path.
+// Path of the objects is the central path.
+type sourceCursor struct {
+	rows *sql.Rows
+	last string
+}
+
+func newSourceCursor(ctx context.Context, db boil.ContextExecutor, where string, args ...interface{}) (*sourceCursor, error) {
+	query, err := sourceQuery(ctx, db, where)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &sourceCursor{rows: rows}, nil
+}
+
+// next returns the next object, or nil at the end.
+func (c *sourceCursor) next() (*cscModels.Object, error) {
+	if !c.rows.Next() {
+		return nil, c.rows.Err()
+	}
+	o := &cscModels.Object{}
+	err := c.rows.Scan(&o.Path, &o.Type, &o.Size, &o.Mtime, &o.MtimeNs, &o.Sha256, &o.Status)
+	if err != nil {
+		return nil, err
+	}
+	// the merge is only correct if both sides agree on the order
+	if c.last != "" && o.Path <= c.last {
+		return nil, fmt.Errorf("csc.db objects are out of order: %q after %q", o.Path, c.last)
+	}
+	c.last = o.Path
+	return o, nil
+}
+
+func (c *sourceCursor) Close() error {
+	return c.rows.Close()
+}
+
+// centralCursor reads the central objects of a namespace ordered by path a
+// page at a time.
+type centralCursor struct {
+	ctx       context.Context
+	exec      boil.ContextExecutor
+	namespace string
+	page      models.ObjectSlice
+	i         int
+	last      string
+	done      bool
+}
+
+func newCentralCursor(ctx context.Context, exec boil.ContextExecutor, namespace string) *centralCursor {
+	return &centralCursor{ctx: ctx, exec: exec, namespace: namespace}
+}
+
+// next returns the next object, or nil at the end.
+func (c *centralCursor) next() (*models.Object, error) {
+	if c.i >= len(c.page) {
+		if c.done {
+			return nil, nil
+		}
+		qs := []qm.QueryMod{qm.Where(models.ObjectColumns.Namespace+" = ?", c.namespace)}
+		if c.last != "" {
+			qs = append(qs, qm.And(models.ObjectColumns.Path+" > ?", c.last))
+		}
+		qs = append(qs, qm.OrderBy(models.ObjectColumns.Path), qm.Limit(centralPageSize))
+		page, err := models.Objects(qs...).All(c.ctx, c.exec)
+		if err != nil {
+			return nil, err
+		}
+		c.page, c.i = page, 0
+		c.done = len(page) < centralPageSize
+		if len(page) == 0 {
+			return nil, nil
+		}
+	}
+	o := c.page[c.i]
+	c.i++
+	if c.last != "" && o.Path <= c.last {
+		return nil, fmt.Errorf("central objects are out of order: %q after %q", o.Path, c.last)
+	}
+	c.last = o.Path
+	return o, nil
+}
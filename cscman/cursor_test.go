@@ -0,0 +1,53 @@
+package cscman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSyncFullMergesPaths(t *testing.T) {
+	centralPageSize = 2
+	t.Cleanup(func() { centralPageSize = 1000 })
+	cm := newTestCscMan(t)
+	namespace := registerTestNamespace(t, cm, "foo")
+	c := newTestCSCDB(t)
+	// "a " and "a\t" sort after "a" by bytes, but a collation padding
+	// spaces puts "a\t" first and "a " at the same place as "a"
+	want := make(map[string]string)
+	for _, path := range []string{"a", "a ", "a\t", "a/b", "a-b", "b"} {
+		c.put(path, "1", testTime)
+		want[path] = "1"
+	}
+	checkSyncResult(t, "first sync", syncTestNamespace(t, cm, namespace, c, true), SyncResult{Inserted: 6})
+	if got := listTestObjects(t, cm, "foo"); !reflect.DeepEqual(got, want) {
+		t.Errorf("objects after the first sync: %q, want %q", got, want)
+	}
+
+	c.put("a ", "2", testTime)
+	c.put("b", "2", testTime)
+	c.remove("a", 0, testTime)
+	c.remove("a/b", 0, testTime)
+	c.put("a\n", "1", testTime)
+	c.put("c", "1", testTime)
+	want = map[string]string{"a ": "2", "a\t": "1", "a\n": "1", "a-b": "1", "b": "2", "c": "1"}
+	checkSyncResult(t, "second sync", syncTestNamespace(t, cm, namespace, c, true), SyncResult{Inserted: 2, Updated: 2, Deleted: 2})
+	if got := listTestObjects(t, cm, "foo"); !reflect.DeepEqual(got, want) {
+		t.Errorf("objects after the second sync: %q, want %q", got, want)
+	}
+}
+
+func TestSyncFullStoresRootsWithBasePaths(t *testing.T) {
+	cm := newTestCscMan(t)
+	namespace := registerTestNamespace(t, cm, "foo")
+	c := newTestCSCDB(t)
+	c.exec("INSERT INTO managements (id, name, base_path, type, mtime, status, description, created_at, updated_at)"+
+		" VALUES (1, 'photos', '/srv/photos/', 'local', ?, 'ok', '', ?, ?)", testTime, testTime, testTime)
+	c.put("a", "1", testTime)
+	c.put("z", "1", testTime)
+	c.exec("UPDATE objects SET management_id = 1 WHERE path = 'z'")
+	checkSyncResult(t, "sync", syncTestNamespace(t, cm, namespace, c, true), SyncResult{Inserted: 2})
+	want := map[string]string{"/srv/photos/z": "1", "a": "1"}
+	if got := listTestObjects(t, cm, "foo"); !reflect.DeepEqual(got, want) {
+		t.Errorf("objects: %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,137 @@
+package cscman
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/taskie/csc"
+)
+
+// legacyTables are the tables created by the migrations that used to be
+// applied by hand before cscman tracked its schema version.
+var legacyTables = map[int]string{
+	0:  "namespaces",
+	10: "objects",
+}
+
+// ErrSchemaOutdated is returned when the database has pending migrations.
+type ErrSchemaOutdated struct {
+	Pending []*csc.Migration
+}
+
+func (e *ErrSchemaOutdated) Error() string {
+	names := make([]string, len(e.Pending))
+	for i, mig := range e.Pending {
+		names[i] = fmt.Sprintf("%02d-%s", mig.Version, mig.Name)
+	}
+	return fmt.Sprintf("database schema is outdated (pending: %s); run `cscman migrate up` first", strings.Join(names, ", "))
+}
+
+// cscManMigrations loads the migrations of the database. Tests replace it
+// to run migrations which SQLite understands.
+var cscManMigrations = csc.CscManMigrations
+
+func (cm *CscMan) migrator() (*csc.Migrator, error) {
+	ms, err := cscManMigrations()
+	if err != nil {
+		return nil, err
+	}
+	return csc.NewMigrator(cm.db, ms), nil
+}
+
+func (cm *CscMan) tableExists(ctx context.Context, name string) (bool, error) {
+	var n int
+	err := cm.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?",
+		name).Scan(&n)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// adoptLegacySchema marks the migrations whose tables already exist as
+// applied when the database has never been migrated by cscman.
+func (cm *CscMan) adoptLegacySchema(ctx context.Context, m *csc.Migrator) error {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return err
+	}
+	if len(applied) != 0 {
+		return nil
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	adopted := make([]*csc.Migration, 0)
+	for _, mig := range pending {
+		table, ok := legacyTables[mig.Version]
+		if !ok {
+			break
+		}
+		exists, err := cm.tableExists(ctx, table)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			break
+		}
+		adopted = append(adopted, mig)
+	}
+	if len(adopted) == 0 {
+		return nil
+	}
+	for _, mig := range adopted {
+		logrus.Infof("Adopting existing schema: %02d-%s", mig.Version, mig.Name)
+	}
+	return m.MarkApplied(ctx, adopted)
+}
+
+// MigrateUp applies all pending migrations.
+func (cm *CscMan) MigrateUp(ctx context.Context) ([]*csc.Migration, error) {
+	m, err := cm.migrator()
+	if err != nil {
+		return nil, err
+	}
+	err = cm.adoptLegacySchema(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return m.Up(ctx)
+}
+
+// MigrateDown reverts the last steps migrations.
+func (cm *CscMan) MigrateDown(ctx context.Context, steps int) ([]*csc.Migration, error) {
+	m, err := cm.migrator()
+	if err != nil {
+		return nil, err
+	}
+	return m.Down(ctx, steps)
+}
+
+func (cm *CscMan) MigrationStatus(ctx context.Context) ([]*csc.MigrationStatus, error) {
+	m, err := cm.migrator()
+	if err != nil {
+		return nil, err
+	}
+	return m.Status(ctx)
+}
+
+// CheckSchema returns ErrSchemaOutdated if any migration is pending.
+func (cm *CscMan) CheckSchema(ctx context.Context) error {
+	m, err := cm.migrator()
+	if err != nil {
+		return err
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) != 0 {
+		return &ErrSchemaOutdated{Pending: pending}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package cscman
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/taskie/csc"
+)
+
+func TestMigrateUpAdoptsLegacySchema(t *testing.T) {
+	ms, err := csc.CscManMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the migrations before cscman tracked its schema and one made later
+	testMigrations := append(ms[:2:2], &csc.Migration{
+		Version: 20,
+		Name:    "test",
+		Up:      []string{"CREATE TABLE test (id INTEGER PRIMARY KEY)"},
+		Down:    []string{"DROP TABLE test"},
+	})
+	cscManMigrations = func() ([]*csc.Migration, error) { return testMigrations, nil }
+	t.Cleanup(func() { cscManMigrations = csc.CscManMigrations })
+
+	db := openTestCentralDB(t)
+	// the tables created by hand, on which 00 and 10 would fail
+	execTestStatements(t, db, testCentralSchema[:2])
+	cm := &CscMan{config: &CscManConfig{}, db: db}
+	registerTestNamespace(t, cm, "foo")
+	ctx := context.Background()
+	applied, err := cm.MigrateUp(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 || applied[0].Version != 20 {
+		t.Errorf("applied %v, want only 20", applied)
+	}
+	versions, err := csc.NewMigrator(db, testMigrations).Applied(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]int, 0, len(versions))
+	for v := range versions {
+		got = append(got, v)
+	}
+	sort.Ints(got)
+	if want := []int{0, 10, 20}; !reflect.DeepEqual(got, want) {
+		t.Errorf("recorded versions %v, want %v", got, want)
+	}
+	_, err = cm.GetNamespace(ctx, "foo")
+	if err != nil {
+		t.Errorf("GetNamespace(foo) after the adoption: %v", err)
+	}
+}
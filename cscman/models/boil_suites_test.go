@@ -14,62 +14,74 @@ import "testing"
 func TestParent(t *testing.T) {
 	t.Run("Namespaces", testNamespaces)
 	t.Run("Objects", testObjects)
+	t.Run("Tokens", testTokens)
 }
 
 func TestDelete(t *testing.T) {
 	t.Run("Namespaces", testNamespacesDelete)
 	t.Run("Objects", testObjectsDelete)
+	t.Run("Tokens", testTokensDelete)
 }
 
 func TestQueryDeleteAll(t *testing.T) {
 	t.Run("Namespaces", testNamespacesQueryDeleteAll)
 	t.Run("Objects", testObjectsQueryDeleteAll)
+	t.Run("Tokens", testTokensQueryDeleteAll)
 }
 
 func TestSliceDeleteAll(t *testing.T) {
 	t.Run("Namespaces", testNamespacesSliceDeleteAll)
 	t.Run("Objects", testObjectsSliceDeleteAll)
+	t.Run("Tokens", testTokensSliceDeleteAll)
 }
 
 func TestExists(t *testing.T) {
 	t.Run("Namespaces", testNamespacesExists)
 	t.Run("Objects", testObjectsExists)
+	t.Run("Tokens", testTokensExists)
 }
 
 func TestFind(t *testing.T) {
 	t.Run("Namespaces", testNamespacesFind)
 	t.Run("Objects", testObjectsFind)
+	t.Run("Tokens", testTokensFind)
 }
 
 func TestBind(t *testing.T) {
 	t.Run("Namespaces", testNamespacesBind)
 	t.Run("Objects", testObjectsBind)
+	t.Run("Tokens", testTokensBind)
 }
 
 func TestOne(t *testing.T) {
 	t.Run("Namespaces", testNamespacesOne)
 	t.Run("Objects", testObjectsOne)
+	t.Run("Tokens", testTokensOne)
 }
 
 func TestAll(t *testing.T) {
 	t.Run("Namespaces", testNamespacesAll)
 	t.Run("Objects", testObjectsAll)
+	t.Run("Tokens", testTokensAll)
 }
 
 func TestCount(t *testing.T) {
 	t.Run("Namespaces", testNamespacesCount)
 	t.Run("Objects", testObjectsCount)
+	t.Run("Tokens", testTokensCount)
 }
 
 func TestHooks(t *testing.T) {
 	t.Run("Namespaces", testNamespacesHooks)
 	t.Run("Objects", testObjectsHooks)
+	t.Run("Tokens", testTokensHooks)
 }
 
 func TestInsert(t *testing.T) {
 	t.Run("Namespaces", testNamespacesInsert)
 	t.Run("Namespaces", testNamespacesInsertWhitelist)
 	t.Run("Objects", testObjectsInsert)
+	t.Run("Tokens", testTokensInsert)
 	t.Run("Objects", testObjectsInsertWhitelist)
 }
 
@@ -116,24 +128,29 @@ func TestToManyRemove(t *testing.T) {}
 func TestReload(t *testing.T) {
 	t.Run("Namespaces", testNamespacesReload)
 	t.Run("Objects", testObjectsReload)
+	t.Run("Tokens", testTokensReload)
 }
 
 func TestReloadAll(t *testing.T) {
 	t.Run("Namespaces", testNamespacesReloadAll)
 	t.Run("Objects", testObjectsReloadAll)
+	t.Run("Tokens", testTokensReloadAll)
 }
 
 func TestSelect(t *testing.T) {
 	t.Run("Namespaces", testNamespacesSelect)
 	t.Run("Objects", testObjectsSelect)
+	t.Run("Tokens", testTokensSelect)
 }
 
 func TestUpdate(t *testing.T) {
 	t.Run("Namespaces", testNamespacesUpdate)
 	t.Run("Objects", testObjectsUpdate)
+	t.Run("Tokens", testTokensUpdate)
 }
 
 func TestSliceUpdateAll(t *testing.T) {
 	t.Run("Namespaces", testNamespacesSliceUpdateAll)
 	t.Run("Objects", testObjectsSliceUpdateAll)
+	t.Run("Tokens", testTokensSliceUpdateAll)
 }
@@ -6,7 +6,9 @@ package models
 var TableNames = struct {
 	Namespaces string
 	Objects    string
+	Tokens     string
 }{
 	Namespaces: "namespaces",
 	Objects:    "objects",
+	Tokens:     "tokens",
 }
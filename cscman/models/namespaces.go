@@ -23,43 +23,58 @@ import (
 
 // Namespace is an object representing the database table.
 type Namespace struct {
-	Name        string    `boil:"name" json:"name" toml:"name" yaml:"name"`
-	URL         string    `boil:"url" json:"url" toml:"url" yaml:"url"`
-	Type        string    `boil:"type" json:"type" toml:"type" yaml:"type"`
-	CSCDBSize   int64     `boil:"csc_db_size" json:"csc_db_size" toml:"csc_db_size" yaml:"csc_db_size"`
-	CSCDBMtime  time.Time `boil:"csc_db_mtime" json:"csc_db_mtime" toml:"csc_db_mtime" yaml:"csc_db_mtime"`
-	CSCDBSha256 string    `boil:"csc_db_sha256" json:"csc_db_sha256" toml:"csc_db_sha256" yaml:"csc_db_sha256"`
-	Status      string    `boil:"status" json:"status" toml:"status" yaml:"status"`
-	Description string    `boil:"description" json:"description" toml:"description" yaml:"description"`
-	CreatedAt   time.Time `boil:"created_at" json:"created_at" toml:"created_at" yaml:"created_at"`
-	UpdatedAt   time.Time `boil:"updated_at" json:"updated_at" toml:"updated_at" yaml:"updated_at"`
+	Name            string    `boil:"name" json:"name" toml:"name" yaml:"name"`
+	URL             string    `boil:"url" json:"url" toml:"url" yaml:"url"`
+	Type            string    `boil:"type" json:"type" toml:"type" yaml:"type"`
+	CSCDBSize       int64     `boil:"csc_db_size" json:"csc_db_size" toml:"csc_db_size" yaml:"csc_db_size"`
+	CSCDBMtime      time.Time `boil:"csc_db_mtime" json:"csc_db_mtime" toml:"csc_db_mtime" yaml:"csc_db_mtime"`
+	CSCDBSha256     string    `boil:"csc_db_sha256" json:"csc_db_sha256" toml:"csc_db_sha256" yaml:"csc_db_sha256"`
+	Etag            string    `boil:"etag" json:"etag" toml:"etag" yaml:"etag"`
+	SyncedUpdatedAt time.Time `boil:"synced_updated_at" json:"synced_updated_at" toml:"synced_updated_at" yaml:"synced_updated_at"`
+	SyncedScanRunID int64     `boil:"synced_scan_run_id" json:"synced_scan_run_id" toml:"synced_scan_run_id" yaml:"synced_scan_run_id"`
+	SyncedAt        time.Time `boil:"synced_at" json:"synced_at" toml:"synced_at" yaml:"synced_at"`
+	Status          string    `boil:"status" json:"status" toml:"status" yaml:"status"`
+	LastError       string    `boil:"last_error" json:"last_error" toml:"last_error" yaml:"last_error"`
+	Description     string    `boil:"description" json:"description" toml:"description" yaml:"description"`
+	CreatedAt       time.Time `boil:"created_at" json:"created_at" toml:"created_at" yaml:"created_at"`
+	UpdatedAt       time.Time `boil:"updated_at" json:"updated_at" toml:"updated_at" yaml:"updated_at"`
 
 	R *namespaceR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L namespaceL  `boil:"-" json:"-" toml:"-" yaml:"-"`
 }
 
 var NamespaceColumns = struct {
-	Name        string
-	URL         string
-	Type        string
-	CSCDBSize   string
-	CSCDBMtime  string
-	CSCDBSha256 string
-	Status      string
-	Description string
-	CreatedAt   string
-	UpdatedAt   string
+	Name            string
+	URL             string
+	Type            string
+	CSCDBSize       string
+	CSCDBMtime      string
+	CSCDBSha256     string
+	Etag            string
+	SyncedUpdatedAt string
+	SyncedScanRunID string
+	SyncedAt        string
+	Status          string
+	LastError       string
+	Description     string
+	CreatedAt       string
+	UpdatedAt       string
 }{
-	Name:        "name",
-	URL:         "url",
-	Type:        "type",
-	CSCDBSize:   "csc_db_size",
-	CSCDBMtime:  "csc_db_mtime",
-	CSCDBSha256: "csc_db_sha256",
-	Status:      "status",
-	Description: "description",
-	CreatedAt:   "created_at",
-	UpdatedAt:   "updated_at",
+	Name:            "name",
+	URL:             "url",
+	Type:            "type",
+	CSCDBSize:       "csc_db_size",
+	CSCDBMtime:      "csc_db_mtime",
+	CSCDBSha256:     "csc_db_sha256",
+	Etag:            "etag",
+	SyncedUpdatedAt: "synced_updated_at",
+	SyncedScanRunID: "synced_scan_run_id",
+	SyncedAt:        "synced_at",
+	Status:          "status",
+	LastError:       "last_error",
+	Description:     "description",
+	CreatedAt:       "created_at",
+	UpdatedAt:       "updated_at",
 }
 
 // Generated where
@@ -111,27 +126,37 @@ func (w whereHelpertime_Time) GTE(x time.Time) qm.QueryMod {
 }
 
 var NamespaceWhere = struct {
-	Name        whereHelperstring
-	URL         whereHelperstring
-	Type        whereHelperstring
-	CSCDBSize   whereHelperint64
-	CSCDBMtime  whereHelpertime_Time
-	CSCDBSha256 whereHelperstring
-	Status      whereHelperstring
-	Description whereHelperstring
-	CreatedAt   whereHelpertime_Time
-	UpdatedAt   whereHelpertime_Time
+	Name            whereHelperstring
+	URL             whereHelperstring
+	Type            whereHelperstring
+	CSCDBSize       whereHelperint64
+	CSCDBMtime      whereHelpertime_Time
+	CSCDBSha256     whereHelperstring
+	Etag            whereHelperstring
+	SyncedUpdatedAt whereHelpertime_Time
+	SyncedScanRunID whereHelperint64
+	SyncedAt        whereHelpertime_Time
+	Status          whereHelperstring
+	LastError       whereHelperstring
+	Description     whereHelperstring
+	CreatedAt       whereHelpertime_Time
+	UpdatedAt       whereHelpertime_Time
 }{
-	Name:        whereHelperstring{field: "`namespaces`.`name`"},
-	URL:         whereHelperstring{field: "`namespaces`.`url`"},
-	Type:        whereHelperstring{field: "`namespaces`.`type`"},
-	CSCDBSize:   whereHelperint64{field: "`namespaces`.`csc_db_size`"},
-	CSCDBMtime:  whereHelpertime_Time{field: "`namespaces`.`csc_db_mtime`"},
-	CSCDBSha256: whereHelperstring{field: "`namespaces`.`csc_db_sha256`"},
-	Status:      whereHelperstring{field: "`namespaces`.`status`"},
-	Description: whereHelperstring{field: "`namespaces`.`description`"},
-	CreatedAt:   whereHelpertime_Time{field: "`namespaces`.`created_at`"},
-	UpdatedAt:   whereHelpertime_Time{field: "`namespaces`.`updated_at`"},
+	Name:            whereHelperstring{field: "`namespaces`.`name`"},
+	URL:             whereHelperstring{field: "`namespaces`.`url`"},
+	Type:            whereHelperstring{field: "`namespaces`.`type`"},
+	CSCDBSize:       whereHelperint64{field: "`namespaces`.`csc_db_size`"},
+	CSCDBMtime:      whereHelpertime_Time{field: "`namespaces`.`csc_db_mtime`"},
+	CSCDBSha256:     whereHelperstring{field: "`namespaces`.`csc_db_sha256`"},
+	Etag:            whereHelperstring{field: "`namespaces`.`etag`"},
+	SyncedUpdatedAt: whereHelpertime_Time{field: "`namespaces`.`synced_updated_at`"},
+	SyncedScanRunID: whereHelperint64{field: "`namespaces`.`synced_scan_run_id`"},
+	SyncedAt:        whereHelpertime_Time{field: "`namespaces`.`synced_at`"},
+	Status:          whereHelperstring{field: "`namespaces`.`status`"},
+	LastError:       whereHelperstring{field: "`namespaces`.`last_error`"},
+	Description:     whereHelperstring{field: "`namespaces`.`description`"},
+	CreatedAt:       whereHelpertime_Time{field: "`namespaces`.`created_at`"},
+	UpdatedAt:       whereHelpertime_Time{field: "`namespaces`.`updated_at`"},
 }
 
 // NamespaceRels is where relationship names are stored.
@@ -151,9 +176,9 @@ func (*namespaceR) NewStruct() *namespaceR {
 type namespaceL struct{}
 
 var (
-	namespaceAllColumns            = []string{"name", "url", "type", "csc_db_size", "csc_db_mtime", "csc_db_sha256", "status", "description", "created_at", "updated_at"}
+	namespaceAllColumns            = []string{"name", "url", "type", "csc_db_size", "csc_db_mtime", "csc_db_sha256", "etag", "synced_updated_at", "synced_scan_run_id", "synced_at", "status", "last_error", "description", "created_at", "updated_at"}
 	namespaceColumnsWithoutDefault = []string{"name", "url", "type", "csc_db_size", "csc_db_mtime", "csc_db_sha256", "status", "description", "created_at", "updated_at"}
-	namespaceColumnsWithDefault    = []string{}
+	namespaceColumnsWithDefault    = []string{"etag", "synced_updated_at", "synced_scan_run_id", "synced_at", "last_error"}
 	namespacePrimaryKeyColumns     = []string{"name"}
 )
 
@@ -568,7 +568,7 @@ func testNamespacesSelect(t *testing.T) {
 }
 
 var (
-	namespaceDBTypes = map[string]string{`Name`: `varchar`, `URL`: `varchar`, `Type`: `varchar`, `CSCDBSize`: `bigint`, `CSCDBMtime`: `datetime`, `CSCDBSha256`: `char`, `Status`: `varchar`, `Description`: `varchar`, `CreatedAt`: `datetime`, `UpdatedAt`: `datetime`}
+	namespaceDBTypes = map[string]string{`Name`: `varchar`, `URL`: `varchar`, `Type`: `varchar`, `CSCDBSize`: `bigint`, `CSCDBMtime`: `datetime`, `CSCDBSha256`: `char`, `Etag`: `varchar`, `SyncedUpdatedAt`: `datetime`, `SyncedScanRunID`: `bigint`, `SyncedAt`: `datetime`, `Status`: `varchar`, `LastError`: `varchar`, `Description`: `varchar`, `CreatedAt`: `datetime`, `UpdatedAt`: `datetime`}
 	_                = bytes.MinRead
 )
 
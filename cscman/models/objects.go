@@ -29,6 +29,7 @@ type Object struct {
 	Type      string    `boil:"type" json:"type" toml:"type" yaml:"type"`
 	Size      int64     `boil:"size" json:"size" toml:"size" yaml:"size"`
 	Mtime     time.Time `boil:"mtime" json:"mtime" toml:"mtime" yaml:"mtime"`
+	MtimeNs   int64     `boil:"mtime_ns" json:"mtime_ns" toml:"mtime_ns" yaml:"mtime_ns"`
 	Sha256    string    `boil:"sha256" json:"sha256" toml:"sha256" yaml:"sha256"`
 	Status    string    `boil:"status" json:"status" toml:"status" yaml:"status"`
 	CreatedAt time.Time `boil:"created_at" json:"created_at" toml:"created_at" yaml:"created_at"`
@@ -45,6 +46,7 @@ var ObjectColumns = struct {
 	Type      string
 	Size      string
 	Mtime     string
+	MtimeNs   string
 	Sha256    string
 	Status    string
 	CreatedAt string
@@ -56,6 +58,7 @@ var ObjectColumns = struct {
 	Type:      "type",
 	Size:      "size",
 	Mtime:     "mtime",
+	MtimeNs:   "mtime_ns",
 	Sha256:    "sha256",
 	Status:    "status",
 	CreatedAt: "created_at",
@@ -87,6 +90,7 @@ var ObjectWhere = struct {
 	Type      whereHelperstring
 	Size      whereHelperint64
 	Mtime     whereHelpertime_Time
+	MtimeNs   whereHelperint64
 	Sha256    whereHelperstring
 	Status    whereHelperstring
 	CreatedAt whereHelpertime_Time
@@ -98,6 +102,7 @@ var ObjectWhere = struct {
 	Type:      whereHelperstring{field: "`objects`.`type`"},
 	Size:      whereHelperint64{field: "`objects`.`size`"},
 	Mtime:     whereHelpertime_Time{field: "`objects`.`mtime`"},
+	MtimeNs:   whereHelperint64{field: "`objects`.`mtime_ns`"},
 	Sha256:    whereHelperstring{field: "`objects`.`sha256`"},
 	Status:    whereHelperstring{field: "`objects`.`status`"},
 	CreatedAt: whereHelpertime_Time{field: "`objects`.`created_at`"},
@@ -121,9 +126,9 @@ func (*objectR) NewStruct() *objectR {
 type objectL struct{}
 
 var (
-	objectAllColumns            = []string{"id", "namespace", "path", "type", "size", "mtime", "sha256", "status", "created_at", "updated_at"}
+	objectAllColumns            = []string{"id", "namespace", "path", "type", "size", "mtime", "mtime_ns", "sha256", "status", "created_at", "updated_at"}
 	objectColumnsWithoutDefault = []string{"namespace", "path", "type", "size", "mtime", "sha256", "status", "created_at", "updated_at"}
-	objectColumnsWithDefault    = []string{"id"}
+	objectColumnsWithDefault    = []string{"id", "mtime_ns"}
 	objectPrimaryKeyColumns     = []string{"id"}
 )
 
@@ -568,7 +568,7 @@ func testObjectsSelect(t *testing.T) {
 }
 
 var (
-	objectDBTypes = map[string]string{`ID`: `int`, `Namespace`: `varchar`, `Path`: `varchar`, `Type`: `varchar`, `Size`: `bigint`, `Mtime`: `datetime`, `Sha256`: `char`, `Status`: `varchar`, `CreatedAt`: `datetime`, `UpdatedAt`: `datetime`}
+	objectDBTypes = map[string]string{`ID`: `int`, `Namespace`: `varchar`, `Path`: `varchar`, `Type`: `varchar`, `Size`: `bigint`, `Mtime`: `datetime`, `MtimeNs`: `bigint`, `Sha256`: `char`, `Status`: `varchar`, `CreatedAt`: `datetime`, `UpdatedAt`: `datetime`}
 	_             = bytes.MinRead
 )
 
@@ -0,0 +1,735 @@
+// Code generated by SQLBoiler 3.5.0 (https://github.com/volatiletech/sqlboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package models
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/volatiletech/sqlboiler/boil"
+	"github.com/volatiletech/sqlboiler/queries"
+	"github.com/volatiletech/sqlboiler/randomize"
+	"github.com/volatiletech/sqlboiler/strmangle"
+)
+
+var (
+	// Relationships sometimes use the reflection helper queries.Equal/queries.Assign
+	// so force a package dependency in case they don't.
+	_ = queries.Equal
+)
+
+func testTokens(t *testing.T) {
+	t.Parallel()
+
+	query := Tokens()
+
+	if query.Query == nil {
+		t.Error("expected a query, got nothing")
+	}
+}
+
+func testTokensDelete(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Token{}
+	if err = randomize.Struct(seed, o, tokenDBTypes, true, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	if rowsAff, err := o.Delete(ctx, tx); err != nil {
+		t.Error(err)
+	} else if rowsAff != 1 {
+		t.Error("should only have deleted one row, but affected:", rowsAff)
+	}
+
+	count, err := Tokens().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 0 {
+		t.Error("want zero records, got:", count)
+	}
+}
+
+func testTokensQueryDeleteAll(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Token{}
+	if err = randomize.Struct(seed, o, tokenDBTypes, true, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	if rowsAff, err := Tokens().DeleteAll(ctx, tx); err != nil {
+		t.Error(err)
+	} else if rowsAff != 1 {
+		t.Error("should only have deleted one row, but affected:", rowsAff)
+	}
+
+	count, err := Tokens().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 0 {
+		t.Error("want zero records, got:", count)
+	}
+}
+
+func testTokensSliceDeleteAll(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Token{}
+	if err = randomize.Struct(seed, o, tokenDBTypes, true, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	slice := TokenSlice{o}
+
+	if rowsAff, err := slice.DeleteAll(ctx, tx); err != nil {
+		t.Error(err)
+	} else if rowsAff != 1 {
+		t.Error("should only have deleted one row, but affected:", rowsAff)
+	}
+
+	count, err := Tokens().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 0 {
+		t.Error("want zero records, got:", count)
+	}
+}
+
+func testTokensExists(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Token{}
+	if err = randomize.Struct(seed, o, tokenDBTypes, true, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	e, err := TokenExists(ctx, tx, o.ID)
+	if err != nil {
+		t.Errorf("Unable to check if Token exists: %s", err)
+	}
+	if !e {
+		t.Errorf("Expected TokenExists to return true, but got false.")
+	}
+}
+
+func testTokensFind(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Token{}
+	if err = randomize.Struct(seed, o, tokenDBTypes, true, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	tokenFound, err := FindToken(ctx, tx, o.ID)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if tokenFound == nil {
+		t.Error("want a record, got nil")
+	}
+}
+
+func testTokensBind(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Token{}
+	if err = randomize.Struct(seed, o, tokenDBTypes, true, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	if err = Tokens().Bind(ctx, tx, o); err != nil {
+		t.Error(err)
+	}
+}
+
+func testTokensOne(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Token{}
+	if err = randomize.Struct(seed, o, tokenDBTypes, true, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	if x, err := Tokens().One(ctx, tx); err != nil {
+		t.Error(err)
+	} else if x == nil {
+		t.Error("expected to get a non nil record")
+	}
+}
+
+func testTokensAll(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	tokenOne := &Token{}
+	tokenTwo := &Token{}
+	if err = randomize.Struct(seed, tokenOne, tokenDBTypes, false, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+	if err = randomize.Struct(seed, tokenTwo, tokenDBTypes, false, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = tokenOne.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+	if err = tokenTwo.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	slice, err := Tokens().All(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(slice) != 2 {
+		t.Error("want 2 records, got:", len(slice))
+	}
+}
+
+func testTokensCount(t *testing.T) {
+	t.Parallel()
+
+	var err error
+	seed := randomize.NewSeed()
+	tokenOne := &Token{}
+	tokenTwo := &Token{}
+	if err = randomize.Struct(seed, tokenOne, tokenDBTypes, false, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+	if err = randomize.Struct(seed, tokenTwo, tokenDBTypes, false, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = tokenOne.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+	if err = tokenTwo.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	count, err := Tokens().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 2 {
+		t.Error("want 2 records, got:", count)
+	}
+}
+
+func tokenBeforeInsertHook(ctx context.Context, e boil.ContextExecutor, o *Token) error {
+	*o = Token{}
+	return nil
+}
+
+func tokenAfterInsertHook(ctx context.Context, e boil.ContextExecutor, o *Token) error {
+	*o = Token{}
+	return nil
+}
+
+func tokenAfterSelectHook(ctx context.Context, e boil.ContextExecutor, o *Token) error {
+	*o = Token{}
+	return nil
+}
+
+func tokenBeforeUpdateHook(ctx context.Context, e boil.ContextExecutor, o *Token) error {
+	*o = Token{}
+	return nil
+}
+
+func tokenAfterUpdateHook(ctx context.Context, e boil.ContextExecutor, o *Token) error {
+	*o = Token{}
+	return nil
+}
+
+func tokenBeforeDeleteHook(ctx context.Context, e boil.ContextExecutor, o *Token) error {
+	*o = Token{}
+	return nil
+}
+
+func tokenAfterDeleteHook(ctx context.Context, e boil.ContextExecutor, o *Token) error {
+	*o = Token{}
+	return nil
+}
+
+func tokenBeforeUpsertHook(ctx context.Context, e boil.ContextExecutor, o *Token) error {
+	*o = Token{}
+	return nil
+}
+
+func tokenAfterUpsertHook(ctx context.Context, e boil.ContextExecutor, o *Token) error {
+	*o = Token{}
+	return nil
+}
+
+func testTokensHooks(t *testing.T) {
+	t.Parallel()
+
+	var err error
+
+	ctx := context.Background()
+	empty := &Token{}
+	o := &Token{}
+
+	seed := randomize.NewSeed()
+	if err = randomize.Struct(seed, o, tokenDBTypes, false); err != nil {
+		t.Errorf("Unable to randomize Token object: %s", err)
+	}
+
+	AddTokenHook(boil.BeforeInsertHook, tokenBeforeInsertHook)
+	if err = o.doBeforeInsertHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doBeforeInsertHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected BeforeInsertHook function to empty object, but got: %#v", o)
+	}
+	tokenBeforeInsertHooks = []TokenHook{}
+
+	AddTokenHook(boil.AfterInsertHook, tokenAfterInsertHook)
+	if err = o.doAfterInsertHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doAfterInsertHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected AfterInsertHook function to empty object, but got: %#v", o)
+	}
+	tokenAfterInsertHooks = []TokenHook{}
+
+	AddTokenHook(boil.AfterSelectHook, tokenAfterSelectHook)
+	if err = o.doAfterSelectHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doAfterSelectHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected AfterSelectHook function to empty object, but got: %#v", o)
+	}
+	tokenAfterSelectHooks = []TokenHook{}
+
+	AddTokenHook(boil.BeforeUpdateHook, tokenBeforeUpdateHook)
+	if err = o.doBeforeUpdateHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doBeforeUpdateHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected BeforeUpdateHook function to empty object, but got: %#v", o)
+	}
+	tokenBeforeUpdateHooks = []TokenHook{}
+
+	AddTokenHook(boil.AfterUpdateHook, tokenAfterUpdateHook)
+	if err = o.doAfterUpdateHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doAfterUpdateHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected AfterUpdateHook function to empty object, but got: %#v", o)
+	}
+	tokenAfterUpdateHooks = []TokenHook{}
+
+	AddTokenHook(boil.BeforeDeleteHook, tokenBeforeDeleteHook)
+	if err = o.doBeforeDeleteHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doBeforeDeleteHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected BeforeDeleteHook function to empty object, but got: %#v", o)
+	}
+	tokenBeforeDeleteHooks = []TokenHook{}
+
+	AddTokenHook(boil.AfterDeleteHook, tokenAfterDeleteHook)
+	if err = o.doAfterDeleteHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doAfterDeleteHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected AfterDeleteHook function to empty object, but got: %#v", o)
+	}
+	tokenAfterDeleteHooks = []TokenHook{}
+
+	AddTokenHook(boil.BeforeUpsertHook, tokenBeforeUpsertHook)
+	if err = o.doBeforeUpsertHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doBeforeUpsertHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected BeforeUpsertHook function to empty object, but got: %#v", o)
+	}
+	tokenBeforeUpsertHooks = []TokenHook{}
+
+	AddTokenHook(boil.AfterUpsertHook, tokenAfterUpsertHook)
+	if err = o.doAfterUpsertHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doAfterUpsertHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected AfterUpsertHook function to empty object, but got: %#v", o)
+	}
+	tokenAfterUpsertHooks = []TokenHook{}
+}
+
+func testTokensInsert(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Token{}
+	if err = randomize.Struct(seed, o, tokenDBTypes, true, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	count, err := Tokens().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 1 {
+		t.Error("want one record, got:", count)
+	}
+}
+
+func testTokensInsertWhitelist(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Token{}
+	if err = randomize.Struct(seed, o, tokenDBTypes, true); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Whitelist(tokenColumnsWithoutDefault...)); err != nil {
+		t.Error(err)
+	}
+
+	count, err := Tokens().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 1 {
+		t.Error("want one record, got:", count)
+	}
+}
+
+func testTokensReload(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Token{}
+	if err = randomize.Struct(seed, o, tokenDBTypes, true, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	if err = o.Reload(ctx, tx); err != nil {
+		t.Error(err)
+	}
+}
+
+func testTokensReloadAll(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Token{}
+	if err = randomize.Struct(seed, o, tokenDBTypes, true, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	slice := TokenSlice{o}
+
+	if err = slice.ReloadAll(ctx, tx); err != nil {
+		t.Error(err)
+	}
+}
+
+func testTokensSelect(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Token{}
+	if err = randomize.Struct(seed, o, tokenDBTypes, true, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	slice, err := Tokens().All(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(slice) != 1 {
+		t.Error("want one record, got:", len(slice))
+	}
+}
+
+var (
+	tokenDBTypes = map[string]string{`ID`: `int`, `Name`: `varchar`, `TokenSha256`: `char`, `Scopes`: `varchar`, `Namespaces`: `varchar`, `CreatedAt`: `datetime`, `UpdatedAt`: `datetime`}
+	_            = bytes.MinRead
+)
+
+func testTokensUpdate(t *testing.T) {
+	t.Parallel()
+
+	if 0 == len(tokenPrimaryKeyColumns) {
+		t.Skip("Skipping table with no primary key columns")
+	}
+	if len(tokenAllColumns) == len(tokenPrimaryKeyColumns) {
+		t.Skip("Skipping table with only primary key columns")
+	}
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Token{}
+	if err = randomize.Struct(seed, o, tokenDBTypes, true, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	count, err := Tokens().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 1 {
+		t.Error("want one record, got:", count)
+	}
+
+	if err = randomize.Struct(seed, o, tokenDBTypes, true, tokenPrimaryKeyColumns...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	if rowsAff, err := o.Update(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	} else if rowsAff != 1 {
+		t.Error("should only affect one row but affected", rowsAff)
+	}
+}
+
+func testTokensSliceUpdateAll(t *testing.T) {
+	t.Parallel()
+
+	if len(tokenAllColumns) == len(tokenPrimaryKeyColumns) {
+		t.Skip("Skipping table with only primary key columns")
+	}
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Token{}
+	if err = randomize.Struct(seed, o, tokenDBTypes, true, tokenColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	count, err := Tokens().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 1 {
+		t.Error("want one record, got:", count)
+	}
+
+	if err = randomize.Struct(seed, o, tokenDBTypes, true, tokenPrimaryKeyColumns...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	// Remove Primary keys and unique columns from what we plan to update
+	var fields []string
+	if strmangle.StringSliceMatch(tokenAllColumns, tokenPrimaryKeyColumns) {
+		fields = tokenAllColumns
+	} else {
+		fields = strmangle.SetComplement(
+			tokenAllColumns,
+			tokenPrimaryKeyColumns,
+		)
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(o))
+	typ := reflect.TypeOf(o).Elem()
+	n := typ.NumField()
+
+	updateMap := M{}
+	for _, col := range fields {
+		for i := 0; i < n; i++ {
+			f := typ.Field(i)
+			if f.Tag.Get("boil") == col {
+				updateMap[col] = value.Field(i).Interface()
+			}
+		}
+	}
+
+	slice := TokenSlice{o}
+	if rowsAff, err := slice.UpdateAll(ctx, tx, updateMap); err != nil {
+		t.Error(err)
+	} else if rowsAff != 1 {
+		t.Error("wanted one record updated but got", rowsAff)
+	}
+}
+
+func testTokensUpsert(t *testing.T) {
+	t.Parallel()
+
+	if len(tokenAllColumns) == len(tokenPrimaryKeyColumns) {
+		t.Skip("Skipping table with only primary key columns")
+	}
+	if len(mySQLTokenUniqueColumns) == 0 {
+		t.Skip("Skipping table with no unique columns to conflict on")
+	}
+
+	seed := randomize.NewSeed()
+	var err error
+	// Attempt the INSERT side of an UPSERT
+	o := Token{}
+	if err = randomize.Struct(seed, &o, tokenDBTypes, false); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Upsert(ctx, tx, boil.Infer(), boil.Infer()); err != nil {
+		t.Errorf("Unable to upsert Token: %s", err)
+	}
+
+	count, err := Tokens().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+	if count != 1 {
+		t.Error("want one record, got:", count)
+	}
+
+	// Attempt the UPDATE side of an UPSERT
+	if err = randomize.Struct(seed, &o, tokenDBTypes, false, tokenPrimaryKeyColumns...); err != nil {
+		t.Errorf("Unable to randomize Token struct: %s", err)
+	}
+
+	if err = o.Upsert(ctx, tx, boil.Infer(), boil.Infer()); err != nil {
+		t.Errorf("Unable to upsert Token: %s", err)
+	}
+
+	count, err = Tokens().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+	if count != 1 {
+		t.Error("want one record, got:", count)
+	}
+}
@@ -0,0 +1,194 @@
+package cscman
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/taskie/csc/cscman/models"
+	"github.com/volatiletech/sqlboiler/boil"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+// NamespaceStats is a namespace with the number and the total size of its
+// objects.
+type NamespaceStats struct {
+	*models.Namespace
+	Objects int64 `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// namespaceStatsSQL counts objects by namespace. Objects of unknown size
+// are not added to the bytes.
+const namespaceStatsSQL = "SELECT namespace, COUNT(*), COALESCE(SUM(CASE WHEN size > 0 THEN size ELSE 0 END), 0) FROM objects"
+
+// ListNamespaceStats returns all the namespaces ordered by name with the
+// statistics of their objects.
+func (cm *CscMan) ListNamespaceStats(ctx context.Context) ([]*NamespaceStats, error) {
+	namespaces, err := cm.ListNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := cm.db.QueryContext(ctx, namespaceStatsSQL+" GROUP BY namespace")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stats := make(map[string]*NamespaceStats)
+	for rows.Next() {
+		var name string
+		st := &NamespaceStats{}
+		err = rows.Scan(&name, &st.Objects, &st.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		stats[name] = st
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*NamespaceStats, len(namespaces))
+	for i, namespace := range namespaces {
+		st, ok := stats[namespace.Name]
+		if !ok {
+			st = &NamespaceStats{}
+		}
+		st.Namespace = namespace
+		res[i] = st
+	}
+	return res, nil
+}
+
+// GetNamespaceStats returns a namespace with the statistics of its objects.
+func (cm *CscMan) GetNamespaceStats(ctx context.Context, name string) (*NamespaceStats, error) {
+	namespace, err := cm.GetNamespace(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	st := &NamespaceStats{Namespace: namespace}
+	var ignored string
+	err = cm.db.QueryRowContext(ctx, namespaceStatsSQL+" WHERE namespace = ? GROUP BY namespace", name).
+		Scan(&ignored, &st.Objects, &st.Bytes)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return st, nil
+}
+
+// ErrNoSuchNamespace is returned when a namespace doesn't exist.
+type ErrNoSuchNamespace struct {
+	Name string
+}
+
+func (e *ErrNoSuchNamespace) Error() string {
+	return "no such namespace: " + e.Name
+}
+
+// GetNamespace is FindNamespace with an ErrNoSuchNamespace if the namespace
+// doesn't exist.
+func (cm *CscMan) GetNamespace(ctx context.Context, name string) (*models.Namespace, error) {
+	namespace, err := cm.FindNamespace(ctx, name)
+	if err == sql.ErrNoRows {
+		return nil, &ErrNoSuchNamespace{Name: name}
+	}
+	return namespace, err
+}
+
+// SetNamespaceURL points a namespace to another csc.db. The transport is
+// chosen by the scheme of url if typ is empty. The next sync compares all
+// the objects because the watermarks belong to the old csc.db.
+func (cm *CscMan) SetNamespaceURL(ctx context.Context, name string, url string, typ string) error {
+	namespace, err := cm.GetNamespace(ctx, name)
+	if err != nil {
+		return err
+	}
+	if typ == "" {
+		typ, err = DetectTransportType(url)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = TransportFor(typ, url)
+	if err != nil {
+		return err
+	}
+	namespace.URL = url
+	namespace.Type = typ
+	namespace.Etag = ""
+	namespace.Status = "new"
+	_, err = namespace.Update(ctx, cm.db, boil.Whitelist(
+		models.NamespaceColumns.URL, models.NamespaceColumns.Type, models.NamespaceColumns.Etag,
+		models.NamespaceColumns.Status, models.NamespaceColumns.UpdatedAt))
+	return err
+}
+
+// SetNamespaceDescription sets the description of a namespace.
+func (cm *CscMan) SetNamespaceDescription(ctx context.Context, name string, description string) error {
+	namespace, err := cm.GetNamespace(ctx, name)
+	if err != nil {
+		return err
+	}
+	if len([]rune(description)) > namespaceDescriptionLength {
+		return fmt.Errorf("description is longer than %d characters", namespaceDescriptionLength)
+	}
+	namespace.Description = description
+	_, err = namespace.Update(ctx, cm.db, boil.Whitelist(models.NamespaceColumns.Description, models.NamespaceColumns.UpdatedAt))
+	return err
+}
+
+// RenameNamespace renames a namespace together with its objects and the
+// tokens naming it.
+func (cm *CscMan) RenameNamespace(ctx context.Context, name string, newName string) error {
+	_, err := cm.GetNamespace(ctx, name)
+	if err != nil {
+		return err
+	}
+	exists, err := models.NamespaceExists(ctx, cm.db, newName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("namespace already exists: %s", newName)
+	}
+	return cm.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := models.Namespaces(qm.Where(models.NamespaceColumns.Name+" = ?", name)).
+			UpdateAll(ctx, tx, models.M{models.NamespaceColumns.Name: newName})
+		if err != nil {
+			return err
+		}
+		_, err = models.Objects(qm.Where(models.ObjectColumns.Namespace+" = ?", name)).
+			UpdateAll(ctx, tx, models.M{models.ObjectColumns.Namespace: newName})
+		if err != nil {
+			return err
+		}
+		return renameTokenNamespace(ctx, tx, name, newName)
+	})
+}
+
+// RemoveNamespace deletes a namespace together with its objects and returns
+// the number of the objects. The namespace is removed from the tokens naming
+// it, so a namespace registered later by the same name isn't exposed.
+func (cm *CscMan) RemoveNamespace(ctx context.Context, name string) (int64, error) {
+	namespace, err := cm.GetNamespace(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	err = cm.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		n, err = models.Objects(qm.Where(models.ObjectColumns.Namespace+" = ?", name)).DeleteAll(ctx, tx)
+		if err != nil {
+			return err
+		}
+		_, err = namespace.Delete(ctx, tx)
+		if err != nil {
+			return err
+		}
+		return renameTokenNamespace(ctx, tx, name, "")
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,101 @@
+package cscman
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// newTestNamespaces returns a CscMan with the namespaces foo and bar, which
+// have an object each.
+func newTestNamespaces(t *testing.T) *CscMan {
+	cm := newTestCscMan(t)
+	for _, name := range []string{"foo", "bar"} {
+		namespace := registerTestNamespace(t, cm, name)
+		c := newTestCSCDB(t)
+		c.put(name+"1", "1", testTime)
+		syncTestNamespace(t, cm, namespace, c, true)
+	}
+	return cm
+}
+
+func TestRenameNamespace(t *testing.T) {
+	cm := newTestNamespaces(t)
+	ctx := context.Background()
+	err := cm.RenameNamespace(ctx, "foo", "bar")
+	if err == nil {
+		t.Error("renamed foo onto bar")
+	}
+	if got, want := listTestObjects(t, cm, "bar"), map[string]string{"bar1": "1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("objects of bar after the rejected rename: %v, want %v", got, want)
+	}
+
+	err = cm.RenameNamespace(ctx, "foo", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cm.GetNamespace(ctx, "foo")
+	if _, ok := err.(*ErrNoSuchNamespace); !ok {
+		t.Errorf("GetNamespace(foo) after the rename: %v", err)
+	}
+	if got := listTestObjects(t, cm, "foo"); len(got) != 0 {
+		t.Errorf("objects of foo after the rename: %v", got)
+	}
+	if got, want := listTestObjects(t, cm, "baz"), map[string]string{"foo1": "1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("objects of baz: %v, want %v", got, want)
+	}
+}
+
+func TestRemoveNamespace(t *testing.T) {
+	cm := newTestNamespaces(t)
+	ctx := context.Background()
+	n, err := cm.RemoveNamespace(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("removed %d objects, want 1", n)
+	}
+	_, err = cm.GetNamespace(ctx, "foo")
+	if _, ok := err.(*ErrNoSuchNamespace); !ok {
+		t.Errorf("GetNamespace(foo) after the removal: %v", err)
+	}
+	if got := listTestObjects(t, cm, "foo"); len(got) != 0 {
+		t.Errorf("objects of foo after the removal: %v", got)
+	}
+	if got, want := listTestObjects(t, cm, "bar"), map[string]string{"bar1": "1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("objects of bar: %v, want %v", got, want)
+	}
+}
+
+func TestRenameNamespaceInTokens(t *testing.T) {
+	cm := newTestNamespaces(t)
+	ctx := context.Background()
+	_, err := cm.CreateToken(ctx, "t1", []string{ScopeRead}, []string{"foo", "f*", "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cm.CreateToken(ctx, "t2", []string{ScopeRead}, []string{"foo2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cm.RenameNamespace(ctx, "foo", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cm.RemoveNamespace(ctx, "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := cm.ListTokens(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string, len(tokens))
+	for _, token := range tokens {
+		got[token.Name] = token.Namespaces
+	}
+	if want := map[string]string{"t1": "baz,f*", "t2": "foo2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("namespaces of tokens: %v, want %v", got, want)
+	}
+}
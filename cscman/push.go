@@ -0,0 +1,237 @@
+package cscman
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/taskie/csc/cscman/models"
+	cscModels "github.com/taskie/csc/models"
+	"github.com/volatiletech/sqlboiler/boil"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+// ErrPushConflict is returned when pushed rows don't continue from the
+// watermarks of the namespace. The host should push its whole csc.db
+// instead.
+var ErrPushConflict = errors.New("pushed rows don't continue from the last sync; push the csc.db")
+
+// ErrInvalidPush is returned for a malformed stream of pushed rows or a
+// pushed file which is not a csc.db.
+type ErrInvalidPush struct {
+	Reason string
+}
+
+func (e *ErrInvalidPush) Error() string {
+	return "invalid push: " + e.Reason
+}
+
+// PushRow is a changed object in a stream of rows pushed by a host, in
+// which Path is the central path. A deleted object has only Path and
+// Deleted.
+type PushRow struct {
+	Path    string    `json:"path"`
+	Deleted bool      `json:"deleted,omitempty"`
+	Type    string    `json:"type,omitempty"`
+	Size    int64     `json:"size,omitempty"`
+	Mtime   time.Time `json:"mtime,omitempty"`
+	MtimeNs int64     `json:"mtime_ns,omitempty"`
+	Sha256  string    `json:"sha256,omitempty"`
+	Status  string    `json:"status,omitempty"`
+}
+
+// PushWatermarks tells which changes a stream of pushed rows holds. It is
+// sent as the query of the request.
+type PushWatermarks struct {
+	// SinceUpdatedAt and SinceScanRunID are the watermarks of the namespace
+	// which the rows continue from.
+	SinceUpdatedAt time.Time
+	SinceScanRunID int64
+	// UpdatedAt and ScanRunID are the watermarks of the csc.db.
+	UpdatedAt time.Time
+	ScanRunID int64
+	// Objects is the number of the objects in the csc.db.
+	Objects int64
+}
+
+func (w *PushWatermarks) Query() url.Values {
+	q := url.Values{}
+	q.Set("since_updated_at", strconv.FormatInt(w.SinceUpdatedAt.Unix(), 10))
+	q.Set("since_scan_run_id", strconv.FormatInt(w.SinceScanRunID, 10))
+	q.Set("updated_at", strconv.FormatInt(w.UpdatedAt.Unix(), 10))
+	q.Set("scan_run_id", strconv.FormatInt(w.ScanRunID, 10))
+	q.Set("objects", strconv.FormatInt(w.Objects, 10))
+	return q
+}
+
+// ParsePushWatermarks parses the query made by Query.
+func ParsePushWatermarks(q url.Values) (*PushWatermarks, error) {
+	var vs [5]int64
+	for i, key := range []string{"since_updated_at", "since_scan_run_id", "updated_at", "scan_run_id", "objects"} {
+		v, err := strconv.ParseInt(q.Get(key), 10, 64)
+		if err != nil {
+			return nil, &ErrInvalidPush{Reason: fmt.Sprintf("%s: %v", key, err)}
+		}
+		vs[i] = v
+	}
+	return &PushWatermarks{
+		SinceUpdatedAt: time.Unix(vs[0], 0).UTC(),
+		SinceScanRunID: vs[1],
+		UpdatedAt:      time.Unix(vs[2], 0).UTC(),
+		ScanRunID:      vs[3],
+		Objects:        vs[4],
+	}, nil
+}
+
+// checkPushedCSCDB returns ErrInvalidPush unless the file at cscdbPath is
+// an intact SQLite database with objects.
+func checkPushedCSCDB(ctx context.Context, cscdbPath string) error {
+	db, err := sql.Open("sqlite3", cscdbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var result string
+	err = db.QueryRowContext(ctx, "PRAGMA quick_check(1)").Scan(&result)
+	if err != nil {
+		return &ErrInvalidPush{Reason: "not a csc.db: " + err.Error()}
+	}
+	if result != "ok" {
+		return &ErrInvalidPush{Reason: "corrupt csc.db: " + result}
+	}
+	ok, err := sqliteTableExists(ctx, db, "objects")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &ErrInvalidPush{Reason: "not a csc.db: no objects table"}
+	}
+	return nil
+}
+
+// IngestCSCDB syncs a namespace with a csc.db uploaded by its host. An
+// upload which isn't a csc.db is rejected with ErrInvalidPush before the
+// namespace is touched.
+func (cm *CscMan) IngestCSCDB(ctx context.Context, name string, cscdbPath string) (*SyncResult, error) {
+	err := cm.CheckSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := cm.GetNamespace(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	err = checkPushedCSCDB(ctx, cscdbPath)
+	if err != nil {
+		return nil, err
+	}
+	return cm.recordSync(ctx, namespace, func() (*SyncResult, error) {
+		return cm.ingestCSCDB(ctx, namespace, cscdbPath, "", false)
+	})
+}
+
+// IngestRows applies a stream of PushRows in JSON to a namespace like an
+// incremental sync. The rows must continue from the watermarks of the
+// namespace and leave as many objects as the csc.db has, or nothing is
+// applied and ErrPushConflict is returned. Deleted paths must not be in the
+// rest of the stream.
+func (cm *CscMan) IngestRows(ctx context.Context, name string, wm *PushWatermarks, r io.Reader) (*SyncResult, error) {
+	err := cm.CheckSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = cm.GetNamespace(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	result := &SyncResult{}
+	err = cm.inTx(ctx, func(tx *sql.Tx) error {
+		// pushes to a namespace wait for each other
+		namespace, err := models.Namespaces(
+			qm.Where(models.NamespaceColumns.Name+" = ?", name), qm.For("UPDATE")).One(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if namespace.Status != "ok" || !namespace.SyncedUpdatedAt.Equal(wm.SinceUpdatedAt) ||
+			namespace.SyncedScanRunID != wm.SinceScanRunID {
+			return ErrPushConflict
+		}
+
+		paths := make([]interface{}, 0, syncDeleteBatchSize)
+		srcs := make([]*cscModels.Object, 0, upsertBatchSize)
+		dec := json.NewDecoder(r)
+		for {
+			var row PushRow
+			err := dec.Decode(&row)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return &ErrInvalidPush{Reason: err.Error()}
+			}
+			if row.Path == "" {
+				return &ErrInvalidPush{Reason: "empty path"}
+			}
+			if row.Deleted {
+				paths = append(paths, row.Path)
+				if len(paths) >= syncDeleteBatchSize {
+					err = deletePaths(ctx, tx, namespace, paths, result)
+					if err != nil {
+						return err
+					}
+					paths = paths[:0]
+				}
+				continue
+			}
+			srcs = append(srcs, &cscModels.Object{
+				Path:    row.Path,
+				Type:    row.Type,
+				Size:    row.Size,
+				Mtime:   row.Mtime,
+				MtimeNs: row.MtimeNs,
+				Sha256:  row.Sha256,
+				Status:  row.Status,
+			})
+			if len(srcs) >= upsertBatchSize {
+				err = upsertSources(ctx, tx, namespace, srcs, result)
+				if err != nil {
+					return err
+				}
+				srcs = srcs[:0]
+			}
+		}
+		err = deletePaths(ctx, tx, namespace, paths, result)
+		if err != nil {
+			return err
+		}
+		err = upsertSources(ctx, tx, namespace, srcs, result)
+		if err != nil {
+			return err
+		}
+
+		consistent, err := centralCountIs(ctx, tx, namespace, wm.Objects)
+		if err != nil {
+			return err
+		}
+		if !consistent {
+			return ErrPushConflict
+		}
+		namespace.SyncedUpdatedAt = wm.UpdatedAt
+		namespace.SyncedScanRunID = wm.ScanRunID
+		namespace.SyncedAt = time.Now()
+		_, err = namespace.Update(ctx, tx, boil.Whitelist(
+			models.NamespaceColumns.SyncedUpdatedAt, models.NamespaceColumns.SyncedScanRunID,
+			models.NamespaceColumns.SyncedAt, models.NamespaceColumns.UpdatedAt))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,228 @@
+package cscman
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/volatiletech/sqlboiler/boil"
+)
+
+// Pusher uploads the catalog of a host to a namespace of "cscman serve".
+type Pusher struct {
+	// URL is the namespace on the server, e.g.
+	// https://cscman.example.local/api/namespaces/NAME.
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+func (p *Pusher) client() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return http.DefaultClient
+}
+
+func (p *Pusher) do(req *http.Request, v interface{}) error {
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+	logrus.Infof("%s %s", req.Method, req.URL)
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusConflict {
+		return ErrPushConflict
+	}
+	if resp.StatusCode != http.StatusOK {
+		var res struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&res) != nil || res.Error == "" {
+			res.Error = resp.Status
+		}
+		return fmt.Errorf("%s %s: %s", req.Method, req.URL, res.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Push pushes the changes of the csc.db since the last sync of the
+// namespace, or the whole csc.db if full is set or the changes can't be
+// told.
+func (p *Pusher) Push(ctx context.Context, db *sql.DB, full bool) (*SyncResult, error) {
+	if !full {
+		result, err := p.PushRows(ctx, db)
+		if err != ErrPushConflict {
+			return result, err
+		}
+		logrus.Info(err)
+	}
+	return p.PushCSCDB(ctx, db)
+}
+
+// PushCSCDB uploads a snapshot of the csc.db.
+func (p *Pusher) PushCSCDB(ctx context.Context, db *sql.DB) (*SyncResult, error) {
+	dir, err := ioutil.TempDir("", "csc")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	// a copy is consistent even while a scan is writing and holds what is
+	// still in the WAL
+	path := filepath.Join(dir, "csc.db")
+	_, err = db.ExecContext(ctx, "VACUUM INTO ?", path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, strings.TrimSuffix(p.URL, "/")+"/csc.db", f)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = fi.Size()
+	req.Header.Set("Content-Type", "application/vnd.sqlite3")
+	result := &SyncResult{}
+	err = p.do(req, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PushRows streams the objects changed since the watermarks of the
+// namespace. It returns ErrPushConflict if they can't be told.
+func (p *Pusher) PushRows(ctx context.Context, db *sql.DB) (*SyncResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	st := &NamespaceStats{}
+	err = p.do(req, st)
+	if err != nil {
+		return nil, err
+	}
+	if st.Namespace == nil || st.Status != "ok" {
+		return nil, ErrPushConflict
+	}
+
+	// the rows are read in a transaction to see a single version of the
+	// csc.db
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	wm, err := readWatermark(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	if reason := wm.inconsistency(st.Namespace); reason != "" {
+		logrus.Infof("Pushing the csc.db: %s", reason)
+		return nil, ErrPushConflict
+	}
+	pwm := &PushWatermarks{
+		SinceUpdatedAt: st.SyncedUpdatedAt,
+		SinceScanRunID: st.SyncedScanRunID,
+		UpdatedAt:      wm.UpdatedAt,
+		ScanRunID:      wm.ScanRunID,
+	}
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM objects").Scan(&pwm.Objects)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pw.CloseWithError(writePushRows(ctx, tx, pwm, pw))
+	}()
+	defer func() {
+		pr.Close()
+		<-done
+	}()
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(p.URL, "/")+"/objects?"+pwm.Query().Encode(), pr)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	result := &SyncResult{}
+	err = p.do(req, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// writePushRows writes the deletions after the watermarks and then the
+// updated objects as PushRows.
+func writePushRows(ctx context.Context, db boil.ContextExecutor, wm *PushWatermarks, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	rows, err := db.QueryContext(ctx, deletedPathsSQL, wm.SinceScanRunID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		row := PushRow{Deleted: true}
+		err = rows.Scan(&row.Path)
+		if err != nil {
+			return err
+		}
+		err = enc.Encode(&row)
+		if err != nil {
+			return err
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+	rows.Close()
+
+	srcs, err := newSourceCursor(ctx, db, updatedSinceSQL, wm.SinceUpdatedAt.Unix())
+	if err != nil {
+		return err
+	}
+	defer srcs.Close()
+	for {
+		src, err := srcs.next()
+		if err != nil {
+			return err
+		}
+		if src == nil {
+			return nil
+		}
+		err = enc.Encode(&PushRow{
+			Path:    src.Path,
+			Type:    src.Type,
+			Size:    src.Size,
+			Mtime:   src.Mtime,
+			MtimeNs: src.MtimeNs,
+			Sha256:  src.Sha256,
+			Status:  src.Status,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
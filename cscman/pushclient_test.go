@@ -0,0 +1,182 @@
+package cscman
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/taskie/csc"
+	"github.com/taskie/csc/cscman/models"
+)
+
+// openTestCSCDB creates a csc.db with objects updated around t0 and
+// deletions of two scan runs.
+func openTestCSCDB(t *testing.T, t0 time.Time) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "csc.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	err = csc.MigrateCscDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokyo := time.FixedZone("JST", 9*60*60)
+	stmts := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"INSERT INTO managements (id, name, base_path, type, mtime, status, description, created_at, updated_at) VALUES (1, 'photos', '/srv/photos/', 'local', ?, 'ok', '', ?, ?)",
+			[]interface{}{t0, t0, t0}},
+		{"INSERT INTO scan_runs (id, started_at, roots, status) VALUES (1, ?, '', 'ok'), (2, ?, '', 'ok'), (3, ?, '', 'running')",
+			[]interface{}{t0, t0, t0}},
+		{"INSERT INTO objects (management_id, path, type, size, mtime, mtime_ns, sha256, status, created_at, updated_at) VALUES (0, 'old.txt', 'b', 1, ?, ?, 'aa', 'ok', ?, ?)",
+			[]interface{}{t0, t0.UnixNano(), t0, t0.Add(-time.Hour)}},
+		{"INSERT INTO objects (management_id, path, type, size, mtime, mtime_ns, sha256, status, created_at, updated_at) VALUES (0, 'new.txt', 'b', 2, ?, ?, 'bb', 'ok', ?, ?)",
+			[]interface{}{t0, t0.UnixNano(), t0, t0.Add(time.Hour)}},
+		{"INSERT INTO objects (management_id, path, type, size, mtime, mtime_ns, sha256, status, created_at, updated_at) VALUES (1, 'p.jpg', 'b', 3, ?, ?, 'cc', 'ok', ?, ?)",
+			[]interface{}{t0, t0.UnixNano(), t0, t0.In(tokyo)}},
+		{"INSERT INTO deletions (scan_run_id, management_id, path, deleted_at) VALUES (1, 0, 'gone1', ?), (2, 1, 'gone2.jpg', ?), (2, 0, 'new.txt', ?)",
+			[]interface{}{t0, t0, t0}},
+	}
+	for _, stmt := range stmts {
+		_, err = db.ExecContext(ctx, stmt.query, stmt.args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestPusherPushRows(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := openTestCSCDB(t, t0)
+
+	var query *PushWatermarks
+	var rows []PushRow
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("unexpected Authorization: %q", r.Header.Get("Authorization"))
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/namespaces/foo":
+			json.NewEncoder(w).Encode(&NamespaceStats{Namespace: &models.Namespace{
+				Name: "foo", Status: "ok", SyncedUpdatedAt: t0, SyncedScanRunID: 1,
+			}})
+		case r.Method == http.MethodPost && r.URL.Path == "/api/namespaces/foo/objects":
+			var err error
+			query, err = ParsePushWatermarks(r.URL.Query())
+			if err != nil {
+				t.Error(err)
+			}
+			sc := bufio.NewScanner(r.Body)
+			for sc.Scan() {
+				var row PushRow
+				err = json.Unmarshal(sc.Bytes(), &row)
+				if err != nil {
+					t.Error(err)
+				}
+				rows = append(rows, row)
+			}
+			json.NewEncoder(w).Encode(&SyncResult{Inserted: 1, Updated: 1, Deleted: 1})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	p := &Pusher{URL: ts.URL + "/api/namespaces/foo", Token: "secret", Client: ts.Client()}
+	result, err := p.Push(context.Background(), db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *result != (SyncResult{Inserted: 1, Updated: 1, Deleted: 1}) {
+		t.Errorf("result = %+v", result)
+	}
+	want := &PushWatermarks{
+		SinceUpdatedAt: t0,
+		SinceScanRunID: 1,
+		UpdatedAt:      t0.Add(time.Hour),
+		ScanRunID:      2,
+		Objects:        3,
+	}
+	if !reflect.DeepEqual(query, want) {
+		t.Errorf("watermarks = %+v, want %+v", query, want)
+	}
+	paths := make([]string, len(rows))
+	for i, row := range rows {
+		paths[i] = row.Path
+		if row.Deleted != (i == 0) {
+			t.Errorf("rows[%d] = %+v", i, row)
+		}
+	}
+	// p.jpg was updated in the second of the watermark in another time zone
+	wantPaths := []string{"/srv/photos/gone2.jpg", "/srv/photos/p.jpg", "new.txt"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("paths = %q, want %q", paths, wantPaths)
+	}
+	if len(rows) == 3 && (rows[2].Sha256 != "bb" || rows[2].Size != 2 || rows[2].MtimeNs != t0.UnixNano()) {
+		t.Errorf("rows[2] = %+v", rows[2])
+	}
+}
+
+func TestPusherFallsBackToCSCDB(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := openTestCSCDB(t, t0)
+
+	uploaded := []byte{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/namespaces/foo":
+			json.NewEncoder(w).Encode(&NamespaceStats{Namespace: &models.Namespace{Name: "foo", Status: "new"}})
+		case r.Method == http.MethodPut && r.URL.Path == "/api/namespaces/foo/csc.db":
+			uploaded, _ = ioutil.ReadAll(r.Body)
+			json.NewEncoder(w).Encode(&SyncResult{Inserted: 3})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	p := &Pusher{URL: ts.URL + "/api/namespaces/foo", Client: ts.Client()}
+	result, err := p.Push(context.Background(), db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 3 {
+		t.Errorf("result = %+v", result)
+	}
+	if string(uploaded[:16]) != "SQLite format 3\x00" {
+		t.Fatalf("uploaded %d bytes which are not a SQLite database", len(uploaded))
+	}
+	path := filepath.Join(t.TempDir(), "csc.db")
+	err = ioutil.WriteFile(path, uploaded, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer copied.Close()
+	var n int
+	err = copied.QueryRow("SELECT COUNT(*) FROM objects").Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("uploaded csc.db has %d objects, want 3", n)
+	}
+}
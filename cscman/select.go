@@ -2,28 +2,83 @@ package cscman
 
 import (
 	"context"
+	"strings"
 
 	"github.com/taskie/csc/cscman/models"
 	"github.com/volatiletech/sqlboiler/queries/qm"
 )
 
-func (cm *CscMan) FindObjectBySha256Prefix(ctx context.Context, sha256Prefix string) ([]*models.Object, error) {
-	fs, err := models.Objects(
-		qm.Where(models.ObjectColumns.Sha256+" LIKE ?", sha256Prefix+"%"),
-		qm.OrderBy(models.ObjectColumns.Sha256+","+models.ObjectColumns.Path)).All(ctx, cm.db)
+// FindObjectBySha256Prefix returns up to limit objects whose sha256s start
+// with sha256Prefix ordered by sha256 and path, or all of them if limit is
+// 0. Unless namespaces is nil, only the objects of them are returned.
+func (cm *CscMan) FindObjectBySha256Prefix(ctx context.Context, sha256Prefix string, namespaces []string, limit int) ([]*models.Object, error) {
+	if namespaces != nil && len(namespaces) == 0 {
+		return []*models.Object{}, nil
+	}
+	qs := namespacesQueryMods(namespaces,
+		qm.Where(models.ObjectColumns.Sha256+" LIKE ?", escapeLike(sha256Prefix)+"%"),
+		qm.OrderBy(models.ObjectColumns.Sha256+","+models.ObjectColumns.Path))
+	fs, err := models.Objects(limitQueryMods(limit, qs...)...).All(ctx, cm.db)
 	if err != nil {
 		return nil, err
 	}
 	return fs, nil
 }
-func (cm *CscMan) FindObjectBySha256s(ctx context.Context, sha256s []string) ([]*models.Object, error) {
+
+// FindObjectBySha256s returns up to limit objects with any of sha256s
+// ordered by sha256 and path, or all of them if limit is 0. Unless
+// namespaces is nil, only the objects of them are returned.
+func (cm *CscMan) FindObjectBySha256s(ctx context.Context, sha256s []string, namespaces []string, limit int) ([]*models.Object, error) {
+	if namespaces != nil && len(namespaces) == 0 {
+		return []*models.Object{}, nil
+	}
 	sha256Interfaces := make([]interface{}, len(sha256s))
 	for i, sha256 := range sha256s {
 		sha256Interfaces[i] = sha256
 	}
-	fs, err := models.Objects(
+	qs := namespacesQueryMods(namespaces,
 		qm.WhereIn(models.ObjectColumns.Sha256+" IN ?", sha256Interfaces...),
-		qm.OrderBy(models.ObjectColumns.Sha256+","+models.ObjectColumns.Path)).All(ctx, cm.db)
+		qm.OrderBy(models.ObjectColumns.Sha256+","+models.ObjectColumns.Path))
+	fs, err := models.Objects(limitQueryMods(limit, qs...)...).All(ctx, cm.db)
+	if err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// namespacesQueryMods restricts qs to namespaces unless it is nil.
+func namespacesQueryMods(namespaces []string, qs ...qm.QueryMod) []qm.QueryMod {
+	if namespaces == nil {
+		return qs
+	}
+	namespaceInterfaces := make([]interface{}, len(namespaces))
+	for i, namespace := range namespaces {
+		namespaceInterfaces[i] = namespace
+	}
+	return append(qs, qm.AndIn(models.ObjectColumns.Namespace+" IN ?", namespaceInterfaces...))
+}
+
+// limitQueryMods appends a limit to qs unless it is 0.
+func limitQueryMods(limit int, qs ...qm.QueryMod) []qm.QueryMod {
+	if limit > 0 {
+		qs = append(qs, qm.Limit(limit))
+	}
+	return qs
+}
+
+// escapeLike escapes the wildcards of LIKE in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// FindObjectsByPathPrefix returns up to limit objects of a namespace whose
+// paths start with pathPrefix, ordered by path.
+func (cm *CscMan) FindObjectsByPathPrefix(ctx context.Context, namespace string, pathPrefix string, limit int) ([]*models.Object, error) {
+	fs, err := models.Objects(
+		qm.Where(models.ObjectColumns.Namespace+" = ?", namespace),
+		qm.And(models.ObjectColumns.Path+" LIKE ?", escapeLike(pathPrefix)+"%"),
+		qm.OrderBy(models.ObjectColumns.Path),
+		qm.Limit(limit)).All(ctx, cm.db)
 	if err != nil {
 		return nil, err
 	}
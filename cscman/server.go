@@ -0,0 +1,401 @@
+package cscman
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/taskie/csc/cscman/models"
+)
+
+const (
+	// serverMinSha256Prefix keeps a lookup by prefix from returning a
+	// large part of all the objects.
+	serverMinSha256Prefix = 4
+	// serverMaxSha256s is the number of sha256s in a batch lookup.
+	serverMaxSha256s  = 1000
+	serverMaxBodySize = 1 << 20
+	// serverMaxCSCDBSize is the size of the largest csc.db which a host
+	// can push.
+	serverMaxCSCDBSize = 1 << 32
+	serverDefaultLimit = 100
+	serverMaxLimit     = 1000
+)
+
+// Server serves lookups of the central objects as JSON with the read scope:
+//
+//	GET  /api/namespaces
+//	GET  /api/namespaces/NAME
+//	GET  /api/namespaces/NAME/objects?prefix=PATH&limit=N
+//	GET  /api/sha256/PREFIX?limit=N
+//	POST /api/sha256?limit=N {"sha256s": ["SHA256", ...]}
+//
+// and pushes by the hosts of namespaces with the push scope:
+//
+//	PUT  /api/namespaces/NAME/csc.db
+//	POST /api/namespaces/NAME/objects?WATERMARKS (PushRows in JSON)
+//
+// Every request needs a bearer token, and only the namespaces which the
+// token has a scope on are shown.
+type Server struct {
+	cm           *CscMan
+	mux          *http.ServeMux
+	authenticate func(ctx context.Context, secret string) (*Access, error)
+}
+
+func NewServer(cm *CscMan) *Server {
+	s := &Server{cm: cm, mux: http.NewServeMux(), authenticate: cm.Authenticate}
+	s.mux.HandleFunc("/api/namespaces", s.withAccess(s.handleNamespaces))
+	s.mux.HandleFunc("/api/namespaces/", s.withAccess(s.handleNamespace))
+	s.mux.HandleFunc("/api/sha256", s.withAccess(s.handleSha256s))
+	s.mux.HandleFunc("/api/sha256/", s.withAccess(s.handleSha256Prefix))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// httpError is an error with the status code of its response.
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		logrus.Debug(err)
+	}
+}
+
+// writeError responds with {"error": MESSAGE}. Errors other than
+// httpError are internal and logged instead of shown.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	message := http.StatusText(status)
+	var herr *httpError
+	var nerr *ErrNoSuchNamespace
+	var perr *ErrInvalidPush
+	switch {
+	case errors.As(err, &herr):
+		status, message = herr.status, herr.message
+	case errors.As(err, &nerr):
+		status, message = http.StatusNotFound, nerr.Error()
+	case errors.As(err, &perr):
+		status, message = http.StatusBadRequest, perr.Error()
+	case errors.Is(err, ErrPushConflict):
+		status, message = http.StatusConflict, err.Error()
+	default:
+		logrus.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+	}
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method || (method == http.MethodGet && r.Method == http.MethodHead) {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, r, &httpError{http.StatusMethodNotAllowed, "method not allowed"})
+	return false
+}
+
+// accessHandler handles a request with the access of its token.
+type accessHandler func(w http.ResponseWriter, r *http.Request, a *Access)
+
+// withAccess authenticates the bearer token of a request before h.
+func (s *Server) withAccess(h accessHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		secret := ""
+		if v := r.Header.Get("Authorization"); strings.HasPrefix(v, "Bearer ") {
+			secret = strings.TrimPrefix(v, "Bearer ")
+		}
+		a, err := s.authenticate(r.Context(), secret)
+		if err == ErrInvalidToken {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cscman"`)
+			err = &httpError{http.StatusUnauthorized, err.Error()}
+		}
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		h(w, r, a)
+	}
+}
+
+// authorize checks scope on a namespace. A namespace without any scope is
+// reported as missing not to tell whether it exists.
+func authorize(a *Access, scope string, name string) error {
+	if !a.Sees(name) {
+		return &ErrNoSuchNamespace{Name: name}
+	}
+	if !a.Allows(scope, name) {
+		return &httpError{http.StatusForbidden, "token " + a.Name + " has no " + scope + " scope on " + name}
+	}
+	return nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSha256Prefix validates a lowercased sha256 or prefix of it, which
+// must not contain wildcards of LIKE.
+func parseSha256Prefix(s string, minLength int) (string, error) {
+	s = strings.ToLower(s)
+	if len(s) < minLength || len(s) > 64 || !isHex(s) {
+		return "", &httpError{http.StatusBadRequest, "invalid sha256: " + strconv.Quote(s)}
+	}
+	return s, nil
+}
+
+func parseLimit(q url.Values) (int, error) {
+	v := q.Get("limit")
+	if v == "" {
+		return serverDefaultLimit, nil
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 || limit > serverMaxLimit {
+		return 0, &httpError{http.StatusBadRequest, "limit must be from 1 to " + strconv.Itoa(serverMaxLimit)}
+	}
+	return limit, nil
+}
+
+func objectsResponse(objs []*models.Object) interface{} {
+	return map[string]interface{}{"objects": objs}
+}
+
+func (s *Server) handleNamespaces(w http.ResponseWriter, r *http.Request, a *Access) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	sts, err := s.cm.ListNamespaceStats(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	visible := []*NamespaceStats{}
+	for _, st := range sts {
+		if a.Sees(st.Name) {
+			visible = append(visible, st)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"namespaces": visible})
+}
+
+func (s *Server) handleNamespace(w http.ResponseWriter, r *http.Request, a *Access) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/namespaces/")
+	i := strings.LastIndex(rest, "/")
+	if i < 0 {
+		s.handleNamespaceStats(w, r, a, rest)
+		return
+	}
+	name := rest[:i]
+	switch {
+	case name == "":
+	case rest[i+1:] == "objects":
+		if !allowMethod(w, r, http.MethodGet, http.MethodPost) {
+			return
+		}
+		if r.Method == http.MethodPost {
+			s.handlePushRows(w, r, a, name)
+		} else {
+			s.handleNamespaceObjects(w, r, a, name)
+		}
+		return
+	case rest[i+1:] == "csc.db":
+		if allowMethod(w, r, http.MethodPut) {
+			s.handlePushCSCDB(w, r, a, name)
+		}
+		return
+	}
+	writeError(w, r, &httpError{http.StatusNotFound, "not found"})
+}
+
+// handleNamespaceStats serves a namespace with any scope because hosts read
+// its watermarks to push.
+func (s *Server) handleNamespaceStats(w http.ResponseWriter, r *http.Request, a *Access, name string) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	if !a.Sees(name) {
+		writeError(w, r, &ErrNoSuchNamespace{Name: name})
+		return
+	}
+	st, err := s.cm.GetNamespaceStats(r.Context(), name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func (s *Server) handleNamespaceObjects(w http.ResponseWriter, r *http.Request, a *Access, name string) {
+	err := authorize(a, ScopeRead, name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	q := r.URL.Query()
+	limit, err := parseLimit(q)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	_, err = s.cm.GetNamespace(r.Context(), name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	objs, err := s.cm.FindObjectsByPathPrefix(r.Context(), name, q.Get("prefix"), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, objectsResponse(objs))
+}
+
+func (s *Server) handlePushCSCDB(w http.ResponseWriter, r *http.Request, a *Access, name string) {
+	err := authorize(a, ScopePush, name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	path, err := tempCSCDB()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer os.Remove(path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	_, err = io.Copy(f, http.MaxBytesReader(w, r.Body, serverMaxCSCDBSize))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		writeError(w, r, &httpError{http.StatusBadRequest, "upload failed: " + err.Error()})
+		return
+	}
+	result, err := s.cm.IngestCSCDB(r.Context(), name, path)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handlePushRows(w http.ResponseWriter, r *http.Request, a *Access, name string) {
+	err := authorize(a, ScopePush, name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	wm, err := ParsePushWatermarks(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	result, err := s.cm.IngestRows(r.Context(), name, wm, r.Body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleSha256Prefix(w http.ResponseWriter, r *http.Request, a *Access) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	prefix, err := parseSha256Prefix(strings.TrimPrefix(r.URL.Path, "/api/sha256/"), serverMinSha256Prefix)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	limit, err := parseLimit(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	namespaces, err := s.cm.readableNamespaces(r.Context(), a)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	objs, err := s.cm.FindObjectBySha256Prefix(r.Context(), prefix, namespaces, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, objectsResponse(objs))
+}
+
+// sha256sRequest is the body of a batch lookup.
+type sha256sRequest struct {
+	Sha256s []string `json:"sha256s"`
+}
+
+func (s *Server) handleSha256s(w http.ResponseWriter, r *http.Request, a *Access) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	limit, err := parseLimit(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var req sha256sRequest
+	err = json.NewDecoder(http.MaxBytesReader(w, r.Body, serverMaxBodySize)).Decode(&req)
+	if err != nil {
+		writeError(w, r, &httpError{http.StatusBadRequest, "invalid request: " + err.Error()})
+		return
+	}
+	if len(req.Sha256s) == 0 || len(req.Sha256s) > serverMaxSha256s {
+		writeError(w, r, &httpError{http.StatusBadRequest, "sha256s must have 1 to " + strconv.Itoa(serverMaxSha256s) + " items"})
+		return
+	}
+	sha256s := make([]string, len(req.Sha256s))
+	for i, v := range req.Sha256s {
+		sha256s[i], err = parseSha256Prefix(v, 64)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	namespaces, err := s.cm.readableNamespaces(r.Context(), a)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	objs, err := s.cm.FindObjectBySha256s(r.Context(), sha256s, namespaces, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, objectsResponse(objs))
+}
@@ -0,0 +1,212 @@
+package cscman
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taskie/csc/cscman/models"
+	"github.com/volatiletech/sqlboiler/queries"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+// newTestServer returns a Server whose only token "secret" may read and
+// push foo and read the namespaces starting with "bar".
+func newTestServer() *Server {
+	s := NewServer(nil)
+	s.authenticate = func(ctx context.Context, secret string) (*Access, error) {
+		switch secret {
+		case "secret":
+			return &Access{Name: "test", Scopes: []string{ScopeRead, ScopePush}, Namespaces: []string{"foo"}}, nil
+		case "reader":
+			return &Access{Name: "reader", Scopes: []string{ScopeRead}, Namespaces: []string{"foo", "bar*"}}, nil
+		}
+		return nil, ErrInvalidToken
+	}
+	return s
+}
+
+func TestServerRejectsInvalidRequests(t *testing.T) {
+	s := newTestServer()
+	cases := []struct {
+		method string
+		path   string
+		token  string
+		body   string
+		status int
+	}{
+		{http.MethodGet, "/api/sha256/abcd", "", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/namespaces", "wrong", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/sha256/ab", "secret", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/sha256/abc%25", "secret", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/sha256/" + strings.Repeat("0", 65), "secret", "", http.StatusBadRequest},
+		{http.MethodPost, "/api/sha256/abcd", "secret", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/sha256", "secret", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/api/sha256", "secret", "{", http.StatusBadRequest},
+		{http.MethodPost, "/api/sha256", "secret", `{"sha256s": []}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/sha256", "secret", `{"sha256s": ["abcd"]}`, http.StatusBadRequest},
+		{http.MethodGet, "/api/sha256/abcd?limit=0", "secret", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/sha256/abcd?limit=1001", "secret", "", http.StatusBadRequest},
+		{http.MethodPost, "/api/sha256?limit=x", "secret", `{"sha256s": ["` + strings.Repeat("0", 64) + `"]}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/namespaces", "secret", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/namespaces/foo/bar", "secret", "", http.StatusNotFound},
+		{http.MethodGet, "/api/namespaces/foo/csc.db", "secret", "", http.StatusMethodNotAllowed},
+		{http.MethodPut, "/api/namespaces/foo/csc.db", "", "", http.StatusUnauthorized},
+		{http.MethodPut, "/api/namespaces/foo/csc.db", "wrong", "", http.StatusUnauthorized},
+		{http.MethodPost, "/api/namespaces/foo/objects", "wrong", "", http.StatusUnauthorized},
+		{http.MethodPost, "/api/namespaces/foo/objects?objects=x", "secret", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/namespaces/foo/objects?limit=0", "secret", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/namespaces/foo/objects?limit=x", "secret", "", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != c.status {
+			t.Errorf("%s %s: status = %d, want %d", c.method, c.path, w.Code, c.status)
+		}
+		var res map[string]string
+		err := json.Unmarshal(w.Body.Bytes(), &res)
+		if err != nil || res["error"] == "" {
+			t.Errorf("%s %s: body = %q", c.method, c.path, w.Body.String())
+		}
+	}
+}
+
+func TestServerLimitsNamespacesByToken(t *testing.T) {
+	s := newTestServer()
+	cases := []struct {
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{http.MethodGet, "/api/namespaces/hr", "secret", http.StatusNotFound},
+		{http.MethodGet, "/api/namespaces/hr/objects", "secret", http.StatusNotFound},
+		{http.MethodPut, "/api/namespaces/hr/csc.db", "secret", http.StatusNotFound},
+		{http.MethodPost, "/api/namespaces/bar1/objects", "reader", http.StatusForbidden},
+		{http.MethodPut, "/api/namespaces/foo/csc.db", "reader", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(""))
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != c.status {
+			t.Errorf("%s %s with %s: status = %d, want %d", c.method, c.path, c.token, w.Code, c.status)
+		}
+	}
+}
+
+func TestAccessAllows(t *testing.T) {
+	a := &Access{Scopes: []string{ScopeRead}, Namespaces: []string{"foo", "bar*"}}
+	cases := []struct {
+		scope     string
+		namespace string
+		want      bool
+	}{
+		{ScopeRead, "foo", true},
+		{ScopeRead, "foo2", false},
+		{ScopeRead, "bar", true},
+		{ScopeRead, "barbaz", true},
+		{ScopeRead, "hr", false},
+		{ScopePush, "foo", false},
+	}
+	for _, c := range cases {
+		if got := a.Allows(c.scope, c.namespace); got != c.want {
+			t.Errorf("Allows(%q, %q) = %v, want %v", c.scope, c.namespace, got, c.want)
+		}
+	}
+}
+
+func TestNamespacesQueryMods(t *testing.T) {
+	cases := []struct {
+		namespaces []string
+		where      string
+		args       []interface{}
+	}{
+		{nil, "WHERE (`sha256` = ?) LIMIT 10;", []interface{}{"x"}},
+		{[]string{"foo"}, "WHERE (`sha256` = ?) AND (`namespace` IN (?)) LIMIT 10;", []interface{}{"x", "foo"}},
+		{[]string{"foo", "bar1"}, "WHERE (`sha256` = ?) AND (`namespace` IN (?,?)) LIMIT 10;", []interface{}{"x", "foo", "bar1"}},
+	}
+	for _, c := range cases {
+		q := models.Objects(limitQueryMods(10, namespacesQueryMods(c.namespaces, qm.Where("`sha256` = ?", "x"))...)...)
+		query, args := queries.BuildQuery(q.Query)
+		if !strings.HasSuffix(query, c.where) || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("namespacesQueryMods(%q): %s %v, want ...%s %v", c.namespaces, query, args, c.where, c.args)
+		}
+	}
+}
+
+func TestPushWatermarksQuery(t *testing.T) {
+	wm := &PushWatermarks{
+		SinceUpdatedAt: time.Unix(1600000000, 0).UTC(),
+		SinceScanRunID: 3,
+		UpdatedAt:      time.Unix(1600000100, 0).UTC(),
+		ScanRunID:      5,
+		Objects:        42,
+	}
+	got, err := ParsePushWatermarks(wm.Query())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, wm) {
+		t.Errorf("got %+v, want %+v", got, wm)
+	}
+}
+
+func TestServerPushCSCDB(t *testing.T) {
+	cm := newTestCscMan(t)
+	ctx := context.Background()
+	_, err := cm.RegisterNamespace(ctx, "foo", "", TransportTypePush)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(cm)
+	s.authenticate = newTestServer().authenticate
+	src := newTestCSCDB(t)
+	src.put("a", "1", testTime)
+	cscdb, err := ioutil.ReadFile(src.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		body   string
+		status int
+	}{
+		{"", http.StatusBadRequest},
+		{"not a csc.db", http.StatusBadRequest},
+		{string(cscdb[:len(cscdb)/2]), http.StatusBadRequest},
+		{string(cscdb), http.StatusOK},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPut, "/api/namespaces/foo/csc.db", strings.NewReader(c.body))
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != c.status {
+			t.Errorf("push of %d bytes: status = %d, want %d: %s", len(c.body), w.Code, c.status, w.Body.String())
+		}
+		if c.status != http.StatusOK {
+			namespace, err := cm.GetNamespace(ctx, "foo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if namespace.Status != "new" {
+				t.Errorf("push of %d bytes: namespace is %s", len(c.body), namespace.Status)
+			}
+		}
+	}
+	if got, want := listTestObjects(t, cm, "foo"), map[string]string{"a": "1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("objects: %v, want %v", got, want)
+	}
+}
@@ -3,9 +3,9 @@ package cscman
 import (
 	"context"
 	"database/sql"
-	"io/ioutil"
 	"os"
-	"os/exec"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/taskie/csc"
@@ -15,147 +15,433 @@ import (
 	"github.com/volatiletech/sqlboiler/queries/qm"
 )
 
-func (cm *CscMan) Rsync(ctx context.Context, remote string) (string, error) {
-	f, err := ioutil.TempFile("", "csc.db")
+// fetch fetches the csc.db of namespace with its transport. If the last
+// sync succeeded and full is not set, the transport may answer that it is not
+// modified.
+func (cm *CscMan) fetch(ctx context.Context, namespace *models.Namespace, full bool) (*FetchResult, error) {
+	t, err := TransportFor(namespace.Type, namespace.URL)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	err = f.Close()
-	if err != nil {
-		return "", err
-	}
-	cmd := exec.CommandContext(ctx, "rsync", "-vzP", remote, f.Name())
-	logrus.Info(cmd)
-	err = cmd.Run()
-	if err != nil {
-		return "", err
+	var prev *FetchState
+	if !full && namespace.Status == "ok" {
+		prev = &FetchState{ETag: namespace.Etag, LastModified: namespace.CSCDBMtime}
 	}
-	return f.Name(), nil
+	return t.Fetch(ctx, namespace.URL, prev)
 }
 
-func (cm *CscMan) RegisterNamespace(ctx context.Context, name string, url string) error {
-	cscdbPath, err := cm.Rsync(ctx, url)
+// RegisterNamespace registers a namespace and syncs it. The transport is
+// chosen by the scheme of url if typ is empty.
+func (cm *CscMan) RegisterNamespace(ctx context.Context, name string, url string, typ string) (*SyncResult, error) {
+	err := cm.CheckSchema(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer os.Remove(cscdbPath)
-
-	fi, err := os.Stat(cscdbPath)
-	if err != nil {
-		return err
+	if typ == "" {
+		typ, err = DetectTransportType(url)
+		if err != nil {
+			return nil, err
+		}
 	}
-
-	cscdbSize := fi.Size()
-	cscdbMtime := fi.ModTime()
 	namespace := models.Namespace{
 		Name:        name,
 		URL:         url,
-		Type:        "",
-		CSCDBSize:   cscdbSize,
-		CSCDBMtime:  cscdbMtime,
+		Type:        typ,
 		CSCDBSha256: "",
 		Status:      "new",
 		Description: "",
 	}
-	err = namespace.Insert(ctx, cm.db, boil.Infer())
+	if typ == TransportTypePush {
+		// synced when its host pushes the csc.db. MySQL rejects the zero
+		// time, so the mtime is the epoch like the defaults of the other
+		// times.
+		namespace.CSCDBMtime = time.Unix(0, 0).UTC()
+		err = namespace.Insert(ctx, cm.db, boil.Infer())
+		if err != nil {
+			return nil, err
+		}
+		return &SyncResult{}, nil
+	}
+	res, err := cm.fetch(ctx, &namespace, true)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = cm.syncWithCSCDBImpl(ctx, &namespace, cscdbPath, fi)
+	defer res.Close()
+
+	fi, err := os.Stat(res.Path)
 	if err != nil {
+		return nil, err
+	}
+
+	namespace.CSCDBSize = fi.Size()
+	namespace.CSCDBMtime = fi.ModTime()
+	namespace.Etag = res.ETag
+	namespace.SyncedAt = time.Now()
+	var result *SyncResult
+	err = cm.inTx(ctx, func(tx *sql.Tx) error {
+		err := namespace.Insert(ctx, tx, boil.Infer())
+		if err != nil {
+			return err
+		}
+		result, err = cm.syncWithCSCDBImpl(ctx, tx, &namespace, res.Path, fi, true)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return result, nil
 }
 
 func (cm *CscMan) FindNamespace(ctx context.Context, name string) (*models.Namespace, error) {
 	return models.Namespaces(qm.Where(models.NamespaceColumns.Name+" = ?", name)).One(ctx, cm.db)
 }
 
-func (cm *CscMan) SyncWithCSCDB(ctx context.Context, namespace *models.Namespace) error {
-	cscdbPath, err := cm.Rsync(ctx, namespace.URL)
+// SyncWithCSCDB syncs namespace with its csc.db. If full is set, all the
+// objects are compared even if the csc.db looks unchanged. A failure is
+// recorded in the status and the last error of namespace.
+func (cm *CscMan) SyncWithCSCDB(ctx context.Context, namespace *models.Namespace, full bool) (*SyncResult, error) {
+	err := cm.CheckSchema(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return cm.recordSync(ctx, namespace, func() (*SyncResult, error) {
+		return cm.syncWithCSCDB(ctx, namespace, full)
+	})
+}
+
+// recordSync runs a sync of namespace by f and records its time, or its
+// error in the status and the last error of namespace.
+func (cm *CscMan) recordSync(ctx context.Context, namespace *models.Namespace, f func() (*SyncResult, error)) (*SyncResult, error) {
+	result, err := f()
+	if err != nil {
+		// the objects are rolled back to the last sync
+		uerr := cm.recordSyncError(ctx, namespace, err)
+		if uerr != nil {
+			logrus.Error(uerr)
+		}
+		return nil, err
+	}
+	namespace.SyncedAt = time.Now()
+	_, err = namespace.Update(ctx, cm.db, boil.Whitelist(models.NamespaceColumns.SyncedAt))
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (cm *CscMan) syncWithCSCDB(ctx context.Context, namespace *models.Namespace, full bool) (*SyncResult, error) {
+	res, err := cm.fetch(ctx, namespace, full)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	if res.NotModified {
+		logrus.Infof("Not modified: %s", namespace.Name)
+		return &SyncResult{}, nil
 	}
-	defer os.Remove(cscdbPath)
+	return cm.ingestCSCDB(ctx, namespace, res.Path, res.ETag, full)
+}
+
+// ingestCSCDB syncs namespace with the csc.db at cscdbPath in a transaction.
+func (cm *CscMan) ingestCSCDB(ctx context.Context, namespace *models.Namespace, cscdbPath string, etag string, full bool) (*SyncResult, error) {
 	fi, err := os.Stat(cscdbPath)
+	if err != nil {
+		return nil, err
+	}
+	var result *SyncResult
+	err = cm.inTx(ctx, func(tx *sql.Tx) error {
+		namespace.Etag = etag
+		result, err = cm.syncWithCSCDBImpl(ctx, tx, namespace, cscdbPath, fi, full)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// namespaceDescriptionLength is the length of namespaces.description and
+// namespaces.last_error.
+const namespaceDescriptionLength = 1000
+
+// recordSyncError marks namespace as failed with the message of cause as its
+// last error until the next successful sync.
+func (cm *CscMan) recordSyncError(ctx context.Context, namespace *models.Namespace, cause error) error {
+	message := []rune(cause.Error())
+	if len(message) > namespaceDescriptionLength {
+		message = message[:namespaceDescriptionLength]
+	}
+	namespace.Status = "error"
+	namespace.LastError = string(message)
+	_, err := namespace.Update(ctx, cm.db, boil.Whitelist(
+		models.NamespaceColumns.Status, models.NamespaceColumns.LastError, models.NamespaceColumns.UpdatedAt))
+	return err
+}
+
+// NamespaceSyncResult is the outcome of the sync of a namespace by
+// SyncNamespaces. Err is set if it failed.
+type NamespaceSyncResult struct {
+	Name   string
+	Result *SyncResult
+	Err    error
+}
+
+// SyncNamespaces syncs namespaces with up to jobs of them at a time. A
+// failure of a namespace doesn't stop the others. The results are in the
+// order of namespaces.
+func (cm *CscMan) SyncNamespaces(ctx context.Context, namespaces []*models.Namespace, jobs int, full bool) ([]*NamespaceSyncResult, error) {
+	err := cm.CheckSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if jobs < 1 {
+		jobs = 1
+	}
+	results := make([]*NamespaceSyncResult, len(namespaces))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, namespace := range namespaces {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, namespace *models.Namespace) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result, err := cm.SyncWithCSCDB(ctx, namespace, full)
+			results[i] = &NamespaceSyncResult{Name: namespace.Name, Result: result, Err: err}
+		}(i, namespace)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// ListNamespaces returns all the namespaces ordered by name.
+func (cm *CscMan) ListNamespaces(ctx context.Context) ([]*models.Namespace, error) {
+	return models.Namespaces(qm.OrderBy(models.NamespaceColumns.Name)).All(ctx, cm.db)
+}
+
+// centralMtime returns the mtime of obj as stored in the DATETIME column of
+// MySQL, which keeps whole seconds in UTC.
+func centralMtime(obj *cscModels.Object) time.Time {
+	return obj.Mtime.UTC().Truncate(time.Second)
+}
+
+// sameMtime compares mtimes by mtime_ns, falling back to whole seconds when
+// neither side knows it.
+func sameMtime(old *models.Object, src *cscModels.Object) bool {
+	if old.MtimeNs != 0 && src.MtimeNs != 0 {
+		return old.MtimeNs == src.MtimeNs
+	}
+	return old.MtimeNs == src.MtimeNs && old.Mtime.Equal(centralMtime(src))
+}
+
+// SyncResult counts the changes made to the central objects table by a
+// sync.
+type SyncResult struct {
+	Inserted int64 `json:"inserted"`
+	Updated  int64 `json:"updated"`
+	Deleted  int64 `json:"deleted"`
+}
+
+// syncDeleteBatchSize is the number of objects deleted by a statement.
+var syncDeleteBatchSize = 500
+
+// deleteObjects deletes the central objects whose IDs are given.
+func deleteObjects(ctx context.Context, exec boil.ContextExecutor, ids []interface{}) (int64, error) {
+	deleted := int64(0)
+	for i := 0; i < len(ids); i += syncDeleteBatchSize {
+		j := i + syncDeleteBatchSize
+		if j > len(ids) {
+			j = len(ids)
+		}
+		n, err := models.Objects(qm.WhereIn(models.ObjectColumns.ID+" IN ?", ids[i:j]...)).DeleteAll(ctx, exec)
+		if err != nil {
+			return deleted, err
+		}
+		deleted += n
+	}
+	return deleted, nil
+}
+
+// inTx runs f in a transaction so that readers see all or none of its
+// changes.
+func (cm *CscMan) inTx(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := cm.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	return cm.syncWithCSCDBImpl(ctx, namespace, cscdbPath, fi)
+	err = f(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
-func (cm *CscMan) syncWithCSCDBImpl(ctx context.Context, namespace *models.Namespace, cscdbPath string, fi os.FileInfo) error {
+// syncWithCSCDBImpl syncs the objects of namespace with a fetched csc.db.
+// Only the rows changed since the watermarks of the last sync are applied
+// unless full is set or the watermarks can't be trusted.
+func (cm *CscMan) syncWithCSCDBImpl(ctx context.Context, tx *sql.Tx, namespace *models.Namespace, cscdbPath string, fi os.FileInfo, full bool) (*SyncResult, error) {
+	result := &SyncResult{}
+	full = full || namespace.Status != "ok"
 	cscdbSize := fi.Size()
 	cscdbMtime := fi.ModTime()
-	if namespace.Status != "new" && cscdbSize == namespace.CSCDBSize && cscdbMtime == namespace.CSCDBMtime {
-		return nil
+	if !full && cscdbSize == namespace.CSCDBSize && cscdbMtime == namespace.CSCDBMtime {
+		return result, nil
 	}
 	cscdbSha256, err := csc.CalcSha256HexString(cscdbPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if namespace.Status != "new" && cscdbSha256 == namespace.CSCDBSha256 {
-		return nil
+	if !full && cscdbSha256 == namespace.CSCDBSha256 {
+		return result, nil
 	}
 
 	db, err := sql.Open("sqlite3", cscdbPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer db.Close()
 
-	olds, err := models.Objects(qm.Where(models.ObjectColumns.Namespace+" = ?", namespace.Name)).All(ctx, cm.db)
+	wm, err := readWatermark(ctx, db)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if !full {
+		if reason := wm.inconsistency(namespace); reason != "" {
+			logrus.Infof("Full resync of %s: %s", namespace.Name, reason)
+			full = true
+		}
+	}
+	if !full {
+		err = cm.syncIncremental(ctx, tx, namespace, db, result)
+		if err != nil {
+			return nil, err
+		}
+		consistent, err := countsMatch(ctx, tx, namespace, db)
+		if err != nil {
+			return nil, err
+		}
+		if !consistent {
+			logrus.Infof("Full resync of %s: object counts differ after incremental sync", namespace.Name)
+			full = true
+		}
+	}
+	if full {
+		err = cm.syncFull(ctx, tx, namespace, db, result)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	namespace.CSCDBSize = cscdbSize
+	namespace.CSCDBMtime = cscdbMtime
+	namespace.CSCDBSha256 = cscdbSha256
+	namespace.SyncedUpdatedAt = wm.UpdatedAt
+	namespace.SyncedScanRunID = wm.ScanRunID
+	namespace.Status = "ok"
+	namespace.LastError = ""
+	_, err = namespace.Update(ctx, tx, boil.Infer())
+	if err != nil {
+		return nil, err
 	}
-	oldMap := make(map[string]*models.Object)
-	for _, old := range olds {
-		oldMap[old.Path] = old
+	return result, nil
+}
+
+// newCentralObject returns the central object of a csc object.
+func newCentralObject(namespace string, src *cscModels.Object) *models.Object {
+	return &models.Object{
+		Namespace: namespace,
+		Path:      src.Path,
+		Type:      src.Type,
+		Size:      src.Size,
+		Mtime:     centralMtime(src),
+		MtimeNs:   src.MtimeNs,
+		Sha256:    src.Sha256,
+		Status:    src.Status,
 	}
+}
 
-	objs, err := cscModels.Objects().All(ctx, db)
+// updateCentralObject copies src to old and tells whether anything changed.
+func updateCentralObject(old *models.Object, src *cscModels.Object) bool {
+	if old.Type == src.Type && old.Size == src.Size && sameMtime(old, src) && old.Sha256 == src.Sha256 && old.Status == src.Status {
+		return false
+	}
+	old.Type = src.Type
+	old.Size = src.Size
+	old.Mtime = centralMtime(src)
+	old.MtimeNs = src.MtimeNs
+	old.Sha256 = src.Sha256
+	old.Status = src.Status
+	return true
+}
+
+// syncFull merges all the objects of a csc.db into the central objects of
+// namespace.
+func (cm *CscMan) syncFull(ctx context.Context, tx *sql.Tx, namespace *models.Namespace, db *sql.DB, result *SyncResult) error {
+	srcs, err := newSourceCursor(ctx, db, "")
 	if err != nil {
 		return err
 	}
+	defer srcs.Close()
+	olds := newCentralCursor(ctx, tx, namespace.Name)
 
-	for _, src := range objs {
-		if old, ok := oldMap[src.Path]; ok {
-			if old.Type != src.Type || old.Size != src.Size || old.Mtime != src.Mtime || old.Sha256 != src.Sha256 || old.Status != src.Status {
-				old.Type = src.Type
-				old.Size = src.Size
-				old.Mtime = src.Mtime
-				old.Sha256 = src.Sha256
-				old.Status = src.Status
-				_, err = old.Update(ctx, cm.db, boil.Infer())
-				if err != nil {
-					return err
-				}
-			}
-		} else {
-			dst := models.Object{
-				Namespace: namespace.Name,
-				Path:      src.Path,
-				Type:      src.Type,
-				Size:      src.Size,
-				Mtime:     src.Mtime,
-				Sha256:    src.Sha256,
-				Status:    src.Status,
+	// both sides are ordered by path and merged so that memory is bounded by
+	// the batches, not by the number of objects
+	changed := make([]*models.Object, 0, upsertBatchSize)
+	goneIDs := make([]interface{}, 0, syncDeleteBatchSize)
+	flush := func(force bool) error {
+		if len(changed) >= upsertBatchSize || (force && len(changed) != 0) {
+			err := upsertObjects(ctx, tx, changed)
+			if err != nil {
+				return err
 			}
-			err = dst.Insert(ctx, cm.db, boil.Infer())
+			changed = changed[:0]
+		}
+		if len(goneIDs) >= syncDeleteBatchSize || (force && len(goneIDs) != 0) {
+			n, err := deleteObjects(ctx, tx, goneIDs)
 			if err != nil {
 				return err
 			}
+			result.Deleted += n
+			goneIDs = goneIDs[:0]
 		}
+		return nil
 	}
 
-	namespace.CSCDBSize = cscdbSize
-	namespace.CSCDBMtime = cscdbMtime
-	namespace.CSCDBSha256 = cscdbSha256
-	namespace.Status = "ok"
-	_, err = namespace.Update(ctx, cm.db, boil.Infer())
+	src, err := srcs.next()
+	if err != nil {
+		return err
+	}
+	old, err := olds.next()
 	if err != nil {
 		return err
 	}
-	return nil
+	for src != nil || old != nil {
+		switch {
+		case old == nil || (src != nil && src.Path < old.Path):
+			changed = append(changed, newCentralObject(namespace.Name, src))
+			result.Inserted++
+			src, err = srcs.next()
+		case src == nil || old.Path < src.Path:
+			// objects removed from the catalog of the host are gone
+			goneIDs = append(goneIDs, old.ID)
+			old, err = olds.next()
+		default:
+			if updateCentralObject(old, src) {
+				changed = append(changed, old)
+				result.Updated++
+			}
+			src, err = srcs.next()
+			if err == nil {
+				old, err = olds.next()
+			}
+		}
+		if err == nil {
+			err = flush(false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return flush(true)
 }
@@ -0,0 +1,186 @@
+package cscman
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/taskie/csc"
+	"github.com/taskie/csc/cscman/models"
+	"github.com/volatiletech/sqlboiler/boil"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+// setTestBatchSizes makes the batches of a sync small for a test.
+func setTestBatchSizes(t *testing.T, upsert int, delete int) {
+	upsertBatchSize, syncDeleteBatchSize = upsert, delete
+	t.Cleanup(func() { upsertBatchSize, syncDeleteBatchSize = 1000, 500 })
+}
+
+// testCSCDB is a csc.db written by a test instead of scans.
+type testCSCDB struct {
+	t    *testing.T
+	path string
+	db   *sql.DB
+}
+
+var testTime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newTestCSCDB(t *testing.T) *testCSCDB {
+	path := filepath.Join(t.TempDir(), "csc.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	err = csc.MigrateCscDB(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCSCDB{t: t, path: path, db: db}
+}
+
+func (c *testCSCDB) exec(query string, args ...interface{}) {
+	_, err := c.db.Exec(query, args...)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// put inserts or updates an object whose content is given by sha256 and
+// which is updated at updatedAt.
+func (c *testCSCDB) put(path string, sha256 string, updatedAt time.Time) {
+	c.exec("INSERT INTO objects (path, type, size, mtime, mtime_ns, sha256, status, created_at, updated_at)"+
+		" VALUES (?, 'b', 1, ?, ?, ?, 'ok', ?, ?)"+
+		" ON CONFLICT (management_id, path) DO UPDATE SET sha256 = excluded.sha256, updated_at = excluded.updated_at",
+		path, testTime, testTime.UnixNano(), sha256, updatedAt, updatedAt)
+}
+
+// remove deletes an object and logs it as deleted by scanRunID unless it is
+// 0.
+func (c *testCSCDB) remove(path string, scanRunID int64, deletedAt time.Time) {
+	c.exec("DELETE FROM objects WHERE path = ?", path)
+	if scanRunID != 0 {
+		c.exec("INSERT INTO deletions (scan_run_id, management_id, path, deleted_at) VALUES (?, 0, ?, ?)",
+			scanRunID, path, deletedAt)
+	}
+}
+
+// addScanRun records a finished scan run.
+func (c *testCSCDB) addScanRun(id int64) {
+	c.exec("INSERT INTO scan_runs (id, started_at, finished_at, roots, status) VALUES (?, ?, ?, '', 'ok')",
+		id, testTime, testTime)
+}
+
+// registerTestNamespace inserts a namespace which has never been synced.
+func registerTestNamespace(t *testing.T, cm *CscMan, name string) *models.Namespace {
+	namespace := &models.Namespace{Name: name, URL: "/srv/" + name + "/csc.db", Type: TransportTypeLocal,
+		CSCDBMtime: time.Unix(0, 0).UTC(), Status: "new"}
+	err := namespace.Insert(context.Background(), cm.db, boil.Infer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return namespace
+}
+
+// syncTestNamespace syncs namespace with c.
+func syncTestNamespace(t *testing.T, cm *CscMan, namespace *models.Namespace, c *testCSCDB, full bool) *SyncResult {
+	result, err := cm.ingestCSCDB(context.Background(), namespace, c.path, "", full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// listTestObjects returns the central objects of a namespace as a map from
+// their paths to their sha256s.
+func listTestObjects(t *testing.T, cm *CscMan, namespace string) map[string]string {
+	objs, err := models.Objects(qm.Where(models.ObjectColumns.Namespace+" = ?", namespace)).All(context.Background(), cm.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := make(map[string]string, len(objs))
+	for _, obj := range objs {
+		m[obj.Path] = obj.Sha256
+	}
+	return m
+}
+
+func checkSyncResult(t *testing.T, name string, got *SyncResult, want SyncResult) {
+	if *got != want {
+		t.Errorf("%s: %+v, want %+v", name, *got, want)
+	}
+}
+
+func TestSyncCounts(t *testing.T) {
+	cm := newTestCscMan(t)
+	namespace := registerTestNamespace(t, cm, "foo")
+	c := newTestCSCDB(t)
+	for _, path := range []string{"a", "b", "c"} {
+		c.put(path, "1", testTime)
+	}
+	checkSyncResult(t, "first sync", syncTestNamespace(t, cm, namespace, c, true), SyncResult{Inserted: 3})
+
+	c.put("b", "2", testTime)
+	c.remove("c", 0, testTime)
+	c.put("d", "1", testTime)
+	checkSyncResult(t, "second sync", syncTestNamespace(t, cm, namespace, c, true), SyncResult{Inserted: 1, Updated: 1, Deleted: 1})
+	checkSyncResult(t, "sync without changes", syncTestNamespace(t, cm, namespace, c, true), SyncResult{})
+
+	want := map[string]string{"a": "1", "b": "2", "d": "1"}
+	if got := listTestObjects(t, cm, "foo"); !reflect.DeepEqual(got, want) {
+		t.Errorf("objects: %v, want %v", got, want)
+	}
+}
+
+func TestSyncBatches(t *testing.T) {
+	setTestBatchSizes(t, 2, 2)
+	cm := newTestCscMan(t)
+	namespace := registerTestNamespace(t, cm, "foo")
+	c := newTestCSCDB(t)
+	want := make(map[string]string)
+	for i := 0; i < 7; i++ {
+		path := fmt.Sprintf("%02d", i)
+		c.put(path, "1", testTime)
+		want[path] = "1"
+	}
+	checkSyncResult(t, "first sync", syncTestNamespace(t, cm, namespace, c, true), SyncResult{Inserted: 7})
+	if got := listTestObjects(t, cm, "foo"); !reflect.DeepEqual(got, want) {
+		t.Errorf("objects: %v, want %v", got, want)
+	}
+
+	for i := 0; i < 5; i++ {
+		path := fmt.Sprintf("%02d", i)
+		c.remove(path, 0, testTime)
+		delete(want, path)
+	}
+	for i := 5; i < 7; i++ {
+		path := fmt.Sprintf("%02d", i)
+		c.put(path, "2", testTime)
+		want[path] = "2"
+	}
+	checkSyncResult(t, "second sync", syncTestNamespace(t, cm, namespace, c, true), SyncResult{Updated: 2, Deleted: 5})
+	if got := listTestObjects(t, cm, "foo"); !reflect.DeepEqual(got, want) {
+		t.Errorf("objects: %v, want %v", got, want)
+	}
+}
+
+func TestRegisterPushNamespace(t *testing.T) {
+	cm := newTestCscMan(t)
+	ctx := context.Background()
+	_, err := cm.RegisterNamespace(ctx, "host1", "", TransportTypePush)
+	if err != nil {
+		t.Fatal(err)
+	}
+	namespace, err := cm.FindNamespace(ctx, "host1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if namespace.Type != TransportTypePush || namespace.Status != "new" || !namespace.CSCDBMtime.Equal(time.Unix(0, 0)) {
+		t.Errorf("registered %+v", namespace)
+	}
+}
@@ -0,0 +1,254 @@
+package cscman
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/taskie/csc/cscman/models"
+	"github.com/volatiletech/sqlboiler/boil"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+const (
+	// ScopeRead allows to look up the objects of a namespace.
+	ScopeRead = "read"
+	// ScopePush allows the host of a namespace to push its catalog.
+	ScopePush = "push"
+)
+
+var tokenScopes = []string{ScopeRead, ScopePush}
+
+const (
+	tokenPrefix = "cscman_"
+	tokenBytes  = 32
+	// tokenNamespacesLength is the length of tokens.namespaces.
+	tokenNamespacesLength = 1000
+)
+
+// ErrInvalidToken is returned for a token which doesn't exist or has been
+// revoked.
+var ErrInvalidToken = errors.New("invalid token")
+
+// ErrNoSuchToken is returned when a token doesn't exist.
+type ErrNoSuchToken struct {
+	Name string
+}
+
+func (e *ErrNoSuchToken) Error() string {
+	return "no such token: " + e.Name
+}
+
+// Access is what the holder of a token may do.
+type Access struct {
+	Name   string
+	Scopes []string
+	// Namespaces are names or globs of the namespaces which the scopes
+	// apply to.
+	Namespaces []string
+}
+
+func newAccess(token *models.Token) *Access {
+	return &Access{
+		Name:       token.Name,
+		Scopes:     splitList(token.Scopes),
+		Namespaces: splitList(token.Namespaces),
+	}
+}
+
+// Allows tells whether scope is granted on a namespace.
+func (a *Access) Allows(scope string, namespace string) bool {
+	for _, s := range a.Scopes {
+		if s == scope {
+			return a.Sees(namespace)
+		}
+	}
+	return false
+}
+
+// Sees tells whether any scope is granted on a namespace.
+func (a *Access) Sees(namespace string) bool {
+	for _, pattern := range a.Namespaces {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readableNamespaces returns the names of the namespaces on which a is
+// granted ScopeRead so that lookups can be restricted to them in SQL.
+func (cm *CscMan) readableNamespaces(ctx context.Context, a *Access) ([]string, error) {
+	namespaces, err := models.Namespaces(qm.Select(models.NamespaceColumns.Name)).All(ctx, cm.db)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		if a.Allows(ScopeRead, namespace.Name) {
+			names = append(names, namespace.Name)
+		}
+	}
+	return names, nil
+}
+
+func splitList(s string) []string {
+	var vs []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+func hashToken(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+func validateTokenScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("no scopes: must be some of %s", strings.Join(tokenScopes, ", "))
+	}
+	for _, scope := range scopes {
+		valid := false
+		for _, s := range tokenScopes {
+			valid = valid || scope == s
+		}
+		if !valid {
+			return fmt.Errorf("invalid scope: %s (must be some of %s)", scope, strings.Join(tokenScopes, ", "))
+		}
+	}
+	return nil
+}
+
+func validateTokenNamespaces(namespaces []string) error {
+	if len(namespaces) == 0 {
+		return errors.New("no namespaces: use \"*\" for all the namespaces")
+	}
+	for _, pattern := range namespaces {
+		if pattern == "" || strings.Contains(pattern, ",") {
+			return fmt.Errorf("invalid namespace: %q", pattern)
+		}
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("invalid namespace: %q: %v", pattern, err)
+		}
+	}
+	if len([]rune(strings.Join(namespaces, ","))) > tokenNamespacesLength {
+		return fmt.Errorf("namespaces are longer than %d characters", tokenNamespacesLength)
+	}
+	return nil
+}
+
+// CreateToken creates a token with scopes on namespaces, which may be globs,
+// and returns its secret. Only the hash of the secret is stored, so it
+// can't be shown again.
+func (cm *CscMan) CreateToken(ctx context.Context, name string, scopes []string, namespaces []string) (string, error) {
+	err := validateTokenScopes(scopes)
+	if err != nil {
+		return "", err
+	}
+	err = validateTokenNamespaces(namespaces)
+	if err != nil {
+		return "", err
+	}
+	exists, err := models.Tokens(qm.Where(models.TokenColumns.Name+" = ?", name)).Exists(ctx, cm.db)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", fmt.Errorf("token already exists: %s", name)
+	}
+	b := make([]byte, tokenBytes)
+	_, err = rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	secret := tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	token := &models.Token{
+		Name:        name,
+		TokenSha256: hashToken(secret),
+		Scopes:      strings.Join(scopes, ","),
+		Namespaces:  strings.Join(namespaces, ","),
+	}
+	err = token.Insert(ctx, cm.db, boil.Infer())
+	if err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// ListTokens returns all the tokens ordered by name.
+func (cm *CscMan) ListTokens(ctx context.Context) ([]*models.Token, error) {
+	return models.Tokens(qm.OrderBy(models.TokenColumns.Name)).All(ctx, cm.db)
+}
+
+// RevokeToken deletes a token.
+func (cm *CscMan) RevokeToken(ctx context.Context, name string) error {
+	n, err := models.Tokens(qm.Where(models.TokenColumns.Name+" = ?", name)).DeleteAll(ctx, cm.db)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return &ErrNoSuchToken{Name: name}
+	}
+	return nil
+}
+
+// Authenticate returns the access of a token, or ErrInvalidToken.
+func (cm *CscMan) Authenticate(ctx context.Context, secret string) (*Access, error) {
+	if secret == "" {
+		return nil, ErrInvalidToken
+	}
+	token, err := models.Tokens(qm.Where(models.TokenColumns.TokenSha256+" = ?", hashToken(secret))).One(ctx, cm.db)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newAccess(token), nil
+}
+
+// renameTokenNamespace replaces a namespace given by name in the tokens,
+// or removes it if newName is empty. Globs are left as they are.
+func renameTokenNamespace(ctx context.Context, tx *sql.Tx, name string, newName string) error {
+	tokens, err := models.Tokens(qm.For("UPDATE")).All(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		namespaces := splitList(token.Namespaces)
+		changed := false
+		renamed := namespaces[:0]
+		for _, v := range namespaces {
+			if v == name {
+				changed = true
+				if newName == "" {
+					continue
+				}
+				v = newName
+			}
+			renamed = append(renamed, v)
+		}
+		if !changed {
+			continue
+		}
+		token.Namespaces = strings.Join(renamed, ",")
+		_, err = token.Update(ctx, tx, boil.Whitelist(models.TokenColumns.Namespaces, models.TokenColumns.UpdatedAt))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package cscman
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	TransportTypeLocal = "local"
+	TransportTypeRsync = "rsync"
+	TransportTypeHTTP  = "http"
+	TransportTypePush  = "push"
+)
+
+// FetchState is what is known about the csc.db fetched last time. A
+// transport may use it to skip fetching an unchanged file.
+type FetchState struct {
+	ETag         string
+	LastModified time.Time
+}
+
+// FetchResult is a fetched csc.db.
+type FetchResult struct {
+	// Path is the local path of the csc.db. It is empty if NotModified.
+	Path        string
+	NotModified bool
+	ETag        string
+	// Temporary tells whether Path is a copy to be removed by Close.
+	Temporary bool
+}
+
+func (r *FetchResult) Close() error {
+	if r.Temporary && r.Path != "" {
+		return os.Remove(r.Path)
+	}
+	return nil
+}
+
+// Transport fetches the csc.db of a namespace from its URL.
+type Transport interface {
+	Type() string
+	Fetch(ctx context.Context, rawurl string, prev *FetchState) (*FetchResult, error)
+}
+
+var transports = map[string]Transport{}
+
+// RegisterTransport makes a transport available by its type.
+func RegisterTransport(t Transport) {
+	transports[t.Type()] = t
+}
+
+func init() {
+	RegisterTransport(&LocalTransport{})
+	RegisterTransport(&RsyncTransport{})
+	RegisterTransport(&HTTPTransport{})
+	RegisterTransport(&PushTransport{})
+}
+
+// DetectTransportType guesses the transport type of a URL from its scheme.
+// URLs without a scheme are rsync/ssh remotes if they look like "host:path"
+// and local paths otherwise. An unknown scheme is an error.
+func DetectTransportType(rawurl string) (string, error) {
+	if i := strings.Index(rawurl, "://"); i >= 0 {
+		switch scheme := strings.ToLower(rawurl[:i]); scheme {
+		case "http", "https":
+			return TransportTypeHTTP, nil
+		case "file":
+			return TransportTypeLocal, nil
+		case "rsync", "ssh":
+			return TransportTypeRsync, nil
+		default:
+			return "", fmt.Errorf("unsupported URL scheme: %s (specify the transport type)", scheme)
+		}
+	}
+	if i := strings.Index(rawurl, ":"); i > 0 && !strings.Contains(rawurl[:i], "/") {
+		return TransportTypeRsync, nil
+	}
+	return TransportTypeLocal, nil
+}
+
+// TransportFor returns the transport of typ, or the one fitting rawurl if
+// typ is empty.
+func TransportFor(typ string, rawurl string) (Transport, error) {
+	if typ == "" {
+		var err error
+		typ, err = DetectTransportType(rawurl)
+		if err != nil {
+			return nil, err
+		}
+	}
+	t, ok := transports[typ]
+	if !ok {
+		return nil, fmt.Errorf("unknown transport type: %s", typ)
+	}
+	return t, nil
+}
+
+func tempCSCDB() (string, error) {
+	f, err := ioutil.TempFile("", "csc.db")
+	if err != nil {
+		return "", err
+	}
+	err = f.Close()
+	if err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// LocalTransport reads a csc.db in place from a path or a file:// URL.
+type LocalTransport struct{}
+
+func (t *LocalTransport) Type() string {
+	return TransportTypeLocal
+}
+
+func (t *LocalTransport) Fetch(ctx context.Context, rawurl string, prev *FetchState) (*FetchResult, error) {
+	path := rawurl
+	if strings.HasPrefix(rawurl, "file://") {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.FromSlash(u.Path)
+	}
+	_, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FetchResult{Path: path}, nil
+}
+
+// RsyncTransport copies a csc.db with rsync from "host:path", rsync:// or
+// ssh:// URLs.
+type RsyncTransport struct{}
+
+func (t *RsyncTransport) Type() string {
+	return TransportTypeRsync
+}
+
+// rsyncArgs converts ssh:// URLs, which rsync does not understand, to
+// "[user@]host:path" with the port given to ssh.
+func rsyncArgs(rawurl string) ([]string, error) {
+	if !strings.HasPrefix(rawurl, "ssh://") {
+		return []string{rawurl}, nil
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	remote := u.Hostname() + ":" + u.Path
+	if u.User != nil {
+		remote = u.User.Username() + "@" + remote
+	}
+	if u.Port() != "" {
+		return []string{"-e", "ssh -p " + u.Port(), remote}, nil
+	}
+	return []string{remote}, nil
+}
+
+func (t *RsyncTransport) Fetch(ctx context.Context, rawurl string, prev *FetchState) (*FetchResult, error) {
+	args, err := rsyncArgs(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	path, err := tempCSCDB()
+	if err != nil {
+		return nil, err
+	}
+	// -t keeps the mtime so that an unchanged csc.db is recognized by its
+	// size and mtime
+	cmd := exec.CommandContext(ctx, "rsync", append(append([]string{"-vztP"}, args...), path)...)
+	logrus.Info(cmd)
+	err = cmd.Run()
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return &FetchResult{Path: path, Temporary: true}, nil
+}
+
+// HTTPTransport downloads a csc.db over HTTP(S) with a conditional GET. The
+// mtime of the downloaded file is set from Last-Modified.
+type HTTPTransport struct {
+	Client *http.Client
+}
+
+func (t *HTTPTransport) Type() string {
+	return TransportTypeHTTP
+}
+
+func (t *HTTPTransport) client() *http.Client {
+	if t.Client != nil {
+		return t.Client
+	}
+	return http.DefaultClient
+}
+
+func (t *HTTPTransport) Fetch(ctx context.Context, rawurl string, prev *FetchState) (*FetchResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil {
+		if prev.ETag != "" {
+			req.Header.Set("If-None-Match", prev.ETag)
+		}
+		if !prev.LastModified.IsZero() {
+			req.Header.Set("If-Modified-Since", prev.LastModified.UTC().Format(http.TimeFormat))
+		}
+	}
+	logrus.Infof("GET %s", rawurl)
+	resp, err := t.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		etag := resp.Header.Get("ETag")
+		if etag == "" && prev != nil {
+			etag = prev.ETag
+		}
+		return &FetchResult{NotModified: true, ETag: etag}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", rawurl, resp.Status)
+	}
+
+	path, err := tempCSCDB()
+	if err != nil {
+		return nil, err
+	}
+	res := &FetchResult{Path: path, ETag: resp.Header.Get("ETag"), Temporary: true}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0600)
+	if err == nil {
+		_, err = io.Copy(f, resp.Body)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err == nil {
+		if lastModified, perr := http.ParseTime(resp.Header.Get("Last-Modified")); perr == nil {
+			err = os.Chtimes(path, lastModified, lastModified)
+		}
+	}
+	if err != nil {
+		res.Close()
+		return nil, err
+	}
+	return res, nil
+}
+
+// PushTransport is the transport of namespaces whose hosts upload their
+// csc.db to "cscman serve". It can't fetch anything.
+type PushTransport struct{}
+
+func (t *PushTransport) Type() string {
+	return TransportTypePush
+}
+
+func (t *PushTransport) Fetch(ctx context.Context, rawurl string, prev *FetchState) (*FetchResult, error) {
+	return nil, fmt.Errorf("the csc.db of a push namespace is uploaded by its host")
+}
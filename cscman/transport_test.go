@@ -0,0 +1,217 @@
+package cscman
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDetectTransportType(t *testing.T) {
+	cases := []struct {
+		url  string
+		want string
+	}{
+		{"csc.db", TransportTypeLocal},
+		{"foo/csc.db", TransportTypeLocal},
+		{"/var/lib/csc/csc.db", TransportTypeLocal},
+		{"./a:b/csc.db", TransportTypeLocal},
+		{"file:///var/lib/csc/csc.db", TransportTypeLocal},
+		{"example.local:csc.db", TransportTypeRsync},
+		{"user@example.local:/srv/csc.db", TransportTypeRsync},
+		{"rsync://example.local/csc/csc.db", TransportTypeRsync},
+		{"ssh://example.local/srv/csc.db", TransportTypeRsync},
+		{"http://example.local/csc.db", TransportTypeHTTP},
+		{"HTTPS://example.local/csc.db", TransportTypeHTTP},
+	}
+	for _, c := range cases {
+		if got, err := DetectTransportType(c.url); err != nil || got != c.want {
+			t.Errorf("DetectTransportType(%q) = %q, %v, want %q", c.url, got, err, c.want)
+		}
+	}
+	for _, url := range []string{"ftp://example.local/csc.db", "s3://bucket/csc.db"} {
+		if got, err := DetectTransportType(url); err == nil {
+			t.Errorf("DetectTransportType(%q) = %q, want an error", url, got)
+		}
+	}
+}
+
+func TestTransportFor(t *testing.T) {
+	tr, err := TransportFor("", "https://example.local/csc.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Type() != TransportTypeHTTP {
+		t.Errorf("got %q", tr.Type())
+	}
+	tr, err = TransportFor(TransportTypeLocal, "https://example.local/csc.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Type() != TransportTypeLocal {
+		t.Errorf("got %q", tr.Type())
+	}
+	_, err = TransportFor("ftp", "ftp://example.local/csc.db")
+	if err == nil {
+		t.Error("expected an error for an unknown type")
+	}
+	_, err = TransportFor("", "ftp://example.local/csc.db")
+	if err == nil {
+		t.Error("expected an error for an unknown scheme")
+	}
+}
+
+func TestRsyncArgs(t *testing.T) {
+	cases := []struct {
+		url  string
+		want []string
+	}{
+		{"example.local:csc.db", []string{"example.local:csc.db"}},
+		{"rsync://example.local/csc/csc.db", []string{"rsync://example.local/csc/csc.db"}},
+		{"ssh://example.local/srv/csc.db", []string{"example.local:/srv/csc.db"}},
+		{"ssh://user@example.local:2222/srv/csc.db", []string{"-e", "ssh -p 2222", "user@example.local:/srv/csc.db"}},
+	}
+	for _, c := range cases {
+		got, err := rsyncArgs(c.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("rsyncArgs(%q) = %q, want %q", c.url, got, c.want)
+		}
+	}
+}
+
+func TestLocalTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cscman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "csc.db")
+	err = ioutil.WriteFile(path, []byte("db"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := &LocalTransport{}
+	for _, url := range []string{path, "file://" + filepath.ToSlash(path)} {
+		res, err := tr.Fetch(context.Background(), url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Path != path || res.Temporary {
+			t.Errorf("Fetch(%q) = %+v", url, res)
+		}
+		err = res.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Close removed the original: %v", err)
+		}
+	}
+
+	_, err = tr.Fetch(context.Background(), filepath.Join(dir, "missing.db"), nil)
+	if err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestHTTPTransport(t *testing.T) {
+	body := []byte("csc.db contents")
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	etag := `"v1"`
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/csc.db" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", modTime.Format(http.TimeFormat))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modTime.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	tr := &HTTPTransport{Client: ts.Client()}
+
+	res, err := tr.Fetch(ctx, ts.URL+"/csc.db", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.NotModified || !res.Temporary || res.ETag != etag {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	got, err := ioutil.ReadFile(res.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(body) {
+		t.Errorf("got %q, want %q", got, body)
+	}
+	fi, err := os.Stat(res.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(modTime) {
+		t.Errorf("mtime = %v, want %v", fi.ModTime(), modTime)
+	}
+	path := res.Path
+	err = res.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("temporary file was not removed: %v", err)
+	}
+
+	res, err = tr.Fetch(ctx, ts.URL+"/csc.db", &FetchState{ETag: etag})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.NotModified || res.Path != "" || res.ETag != etag {
+		t.Errorf("expected not modified by ETag: %+v", res)
+	}
+
+	res, err = tr.Fetch(ctx, ts.URL+"/csc.db", &FetchState{LastModified: modTime})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.NotModified {
+		t.Errorf("expected not modified by Last-Modified: %+v", res)
+	}
+
+	etag = `"v2"`
+	res, err = tr.Fetch(ctx, ts.URL+"/csc.db", &FetchState{ETag: `"v1"`, LastModified: modTime.Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if res.NotModified || res.ETag != etag {
+		t.Errorf("expected a new version: %+v", res)
+	}
+
+	_, err = tr.Fetch(ctx, ts.URL+"/missing.db", nil)
+	if err == nil {
+		t.Error("expected an error for 404")
+	}
+	if requests != 5 {
+		t.Errorf("requests = %d, want 5", requests)
+	}
+}
@@ -0,0 +1,97 @@
+package cscman
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/taskie/csc/cscman/models"
+	"github.com/volatiletech/sqlboiler/boil"
+	"github.com/volatiletech/sqlboiler/strmangle"
+)
+
+var (
+	// upsertBatchSize is the number of objects written by a statement. It
+	// keeps the placeholders well under the limit of MySQL.
+	upsertBatchSize = 1000
+
+	upsertObjectColumns = []string{
+		models.ObjectColumns.Namespace,
+		models.ObjectColumns.Path,
+		models.ObjectColumns.Type,
+		models.ObjectColumns.Size,
+		models.ObjectColumns.Mtime,
+		models.ObjectColumns.MtimeNs,
+		models.ObjectColumns.Sha256,
+		models.ObjectColumns.Status,
+		models.ObjectColumns.CreatedAt,
+		models.ObjectColumns.UpdatedAt,
+	}
+	// created_at is kept by rows which already exist
+	upsertObjectUpdateColumns = []string{
+		models.ObjectColumns.Type,
+		models.ObjectColumns.Size,
+		models.ObjectColumns.Mtime,
+		models.ObjectColumns.MtimeNs,
+		models.ObjectColumns.Sha256,
+		models.ObjectColumns.Status,
+		models.ObjectColumns.UpdatedAt,
+	}
+)
+
+// buildMultiUpsertQueryMySQL builds the multi-row form of the upsert of
+// sqlboiler's mysql_upsert.go: INSERT ... ON DUPLICATE KEY UPDATE with a
+// VALUES group per row.
+func buildMultiUpsertQueryMySQL(tableName string, columns []string, update []string, rows int) string {
+	buf := strmangle.GetBuffer()
+	defer strmangle.PutBuffer(buf)
+
+	fmt.Fprintf(
+		buf,
+		"INSERT INTO %s (%s) VALUES %s ON DUPLICATE KEY UPDATE ",
+		strmangle.IdentQuote('`', '`', tableName),
+		strings.Join(strmangle.IdentQuoteSlice('`', '`', columns), ","),
+		strmangle.Placeholders(false, len(columns)*rows, 1, len(columns)),
+	)
+	for i, v := range update {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		quoted := strmangle.IdentQuote('`', '`', v)
+		buf.WriteString(quoted)
+		buf.WriteString(" = VALUES(")
+		buf.WriteString(quoted)
+		buf.WriteByte(')')
+	}
+	return buf.String()
+}
+
+// upsertObjects inserts or updates objects by (namespace, path) in batches.
+func upsertObjects(ctx context.Context, exec boil.ContextExecutor, objs []*models.Object) error {
+	now := time.Now().In(boil.GetLocation())
+	for i := 0; i < len(objs); i += upsertBatchSize {
+		j := i + upsertBatchSize
+		if j > len(objs) {
+			j = len(objs)
+		}
+		args := make([]interface{}, 0, (j-i)*len(upsertObjectColumns))
+		for _, o := range objs[i:j] {
+			if o.CreatedAt.IsZero() {
+				o.CreatedAt = now
+			}
+			o.UpdatedAt = now
+			args = append(args, o.Namespace, o.Path, o.Type, o.Size, o.Mtime, o.MtimeNs, o.Sha256, o.Status,
+				o.CreatedAt, o.UpdatedAt)
+		}
+		query := buildMultiUpsertQueryMySQL(models.TableNames.Objects, upsertObjectColumns, upsertObjectUpdateColumns, j-i)
+		if boil.DebugMode {
+			fmt.Fprintln(boil.DebugWriter, query)
+		}
+		_, err := exec.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package cscman
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/taskie/csc/cscman/models"
+)
+
+func TestBuildMultiUpsertQueryMySQL(t *testing.T) {
+	got := buildMultiUpsertQueryMySQL("objects", []string{"namespace", "path", "size"}, []string{"size"}, 2)
+	want := "INSERT INTO `objects` (`namespace`,`path`,`size`) VALUES (?,?,?),(?,?,?) ON DUPLICATE KEY UPDATE `size` = VALUES(`size`)"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUpsertObjectsSplitsBatches(t *testing.T) {
+	setTestBatchSizes(t, 2, 2)
+	cm := newTestCscMan(t)
+	ctx := context.Background()
+	objs := make([]*models.Object, 5)
+	for i := range objs {
+		objs[i] = &models.Object{Namespace: "foo", Path: fmt.Sprintf("%d", i), Type: "b", Size: 1, Sha256: "1", Status: "ok"}
+	}
+	err := upsertObjects(ctx, cm.db, objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// existing objects are updated instead of inserted again
+	objs[2].Sha256 = "2"
+	err = upsertObjects(ctx, cm.db, objs[2:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"0": "1", "1": "1", "2": "2", "3": "1", "4": "1"}
+	if got := listTestObjects(t, cm, "foo"); !reflect.DeepEqual(got, want) {
+		t.Errorf("objects: %v, want %v", got, want)
+	}
+}
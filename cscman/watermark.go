@@ -0,0 +1,221 @@
+package cscman
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/taskie/csc/cscman/models"
+	cscModels "github.com/taskie/csc/models"
+	"github.com/volatiletech/sqlboiler/boil"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+// watermark is how far a csc.db has been written, as recorded by a sync.
+type watermark struct {
+	// UpdatedAt is the highest updated_at of objects and deletions in whole
+	// seconds.
+	UpdatedAt time.Time
+	// ScanRunID is the last scan run which is not running. Deletions of
+	// later runs may not be complete yet.
+	ScanRunID int64
+	// MaxScanRunID is the last scan run of any status.
+	MaxScanRunID int64
+	// Incremental tells whether the csc.db has the deletion log.
+	Incremental bool
+}
+
+func readWatermark(ctx context.Context, db boil.ContextExecutor) (*watermark, error) {
+	wm := &watermark{UpdatedAt: time.Unix(0, 0).UTC()}
+	ok, err := sqliteTableExists(ctx, db, "deletions")
+	if err != nil || !ok {
+		return wm, err
+	}
+	wm.Incremental = true
+	// csc writes times with the local offset, which strftime converts to UTC
+	var updatedAt sql.NullInt64
+	err = db.QueryRowContext(ctx, "SELECT max(t) FROM ("+
+		"SELECT max(CAST(strftime('%s', updated_at) AS INTEGER)) AS t FROM objects"+
+		" UNION ALL SELECT max(CAST(strftime('%s', deleted_at) AS INTEGER)) FROM deletions)").Scan(&updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if updatedAt.Valid {
+		wm.UpdatedAt = time.Unix(updatedAt.Int64, 0).UTC()
+	}
+	err = db.QueryRowContext(ctx, "SELECT ifnull(max(CASE WHEN status != 'running' THEN id END), 0), ifnull(max(id), 0) FROM scan_runs").
+		Scan(&wm.ScanRunID, &wm.MaxScanRunID)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// inconsistency returns why the rows newer than the watermarks of namespace
+// may not be all the changes since its last sync, or "" if they are.
+func (wm *watermark) inconsistency(namespace *models.Namespace) string {
+	switch {
+	case !wm.Incremental:
+		return "csc.db has no deletion log"
+	case namespace.SyncedScanRunID == 0 && namespace.SyncedUpdatedAt.Unix() <= 0:
+		return "no watermarks"
+	case wm.MaxScanRunID < namespace.SyncedScanRunID:
+		return "scan runs went back"
+	case wm.UpdatedAt.Before(namespace.SyncedUpdatedAt):
+		return "updated_at went back"
+	}
+	return ""
+}
+
+// deletedPathsSQL selects the central paths of the objects deleted by the
+// scan runs after a watermark. Paths which exist again are left to the
+// updated objects.
+var deletedPathsSQL = "SELECT DISTINCT " + centralPathSQL("d") +
+	" FROM deletions d LEFT JOIN managements m ON m.id = d.management_id" +
+	" WHERE d.scan_run_id > ? AND NOT EXISTS" +
+	" (SELECT 1 FROM objects o WHERE o.management_id = d.management_id AND o.path = d.path)"
+
+// updatedSinceSQL filters the objects updated in or after a second.
+const updatedSinceSQL = "CAST(strftime('%s', o.updated_at) AS INTEGER) >= ?"
+
+// syncIncremental applies the deletions logged by the scan runs after the
+// watermark of namespace and the objects updated since it. Objects updated
+// in the same second as the watermark are applied again, which changes
+// nothing.
+func (cm *CscMan) syncIncremental(ctx context.Context, tx *sql.Tx, namespace *models.Namespace, db *sql.DB, result *SyncResult) error {
+	rows, err := db.QueryContext(ctx, deletedPathsSQL, namespace.SyncedScanRunID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	paths := make([]interface{}, 0, syncDeleteBatchSize)
+	for rows.Next() {
+		var path string
+		err = rows.Scan(&path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, path)
+		if len(paths) >= syncDeleteBatchSize {
+			err = deletePaths(ctx, tx, namespace, paths, result)
+			if err != nil {
+				return err
+			}
+			paths = paths[:0]
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+	err = deletePaths(ctx, tx, namespace, paths, result)
+	if err != nil {
+		return err
+	}
+
+	srcs, err := newSourceCursor(ctx, db, updatedSinceSQL, namespace.SyncedUpdatedAt.Unix())
+	if err != nil {
+		return err
+	}
+	defer srcs.Close()
+	batch := make([]*cscModels.Object, 0, upsertBatchSize)
+	for {
+		src, err := srcs.next()
+		if err != nil {
+			return err
+		}
+		if src == nil {
+			break
+		}
+		batch = append(batch, src)
+		if len(batch) >= upsertBatchSize {
+			err = upsertSources(ctx, tx, namespace, batch, result)
+			if err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	return upsertSources(ctx, tx, namespace, batch, result)
+}
+
+// deletePaths deletes the central objects of namespace at paths.
+func deletePaths(ctx context.Context, tx *sql.Tx, namespace *models.Namespace, paths []interface{}, result *SyncResult) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	olds, err := models.Objects(
+		qm.Where(models.ObjectColumns.Namespace+" = ?", namespace.Name),
+		qm.AndIn(models.ObjectColumns.Path+" IN ?", paths...),
+	).All(ctx, tx)
+	if err != nil {
+		return err
+	}
+	ids := make([]interface{}, 0, len(olds))
+	for _, old := range olds {
+		ids = append(ids, old.ID)
+	}
+	n, err := deleteObjects(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+	result.Deleted += n
+	return nil
+}
+
+// upsertSources inserts or updates the central objects of namespace from
+// csc objects whose paths are central paths.
+func upsertSources(ctx context.Context, tx *sql.Tx, namespace *models.Namespace, srcs []*cscModels.Object, result *SyncResult) error {
+	if len(srcs) == 0 {
+		return nil
+	}
+	paths := make([]interface{}, 0, len(srcs))
+	for _, src := range srcs {
+		paths = append(paths, src.Path)
+	}
+	olds, err := models.Objects(
+		qm.Where(models.ObjectColumns.Namespace+" = ?", namespace.Name),
+		qm.AndIn(models.ObjectColumns.Path+" IN ?", paths...),
+	).All(ctx, tx)
+	if err != nil {
+		return err
+	}
+	oldMap := make(map[string]*models.Object, len(olds))
+	for _, old := range olds {
+		oldMap[old.Path] = old
+	}
+	changed := make([]*models.Object, 0, len(srcs))
+	for _, src := range srcs {
+		if old, ok := oldMap[src.Path]; ok {
+			if updateCentralObject(old, src) {
+				changed = append(changed, old)
+				result.Updated++
+			}
+		} else {
+			changed = append(changed, newCentralObject(namespace.Name, src))
+			result.Inserted++
+		}
+	}
+	return upsertObjects(ctx, tx, changed)
+}
+
+// countsMatch tells whether namespace has as many central objects as the
+// csc.db. A mismatch after an incremental sync means that some changes were
+// not logged, e.g. objects removed with their root.
+func countsMatch(ctx context.Context, tx *sql.Tx, namespace *models.Namespace, db boil.ContextExecutor) (bool, error) {
+	var n int64
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM objects").Scan(&n)
+	if err != nil {
+		return false, err
+	}
+	return centralCountIs(ctx, tx, namespace, n)
+}
+
+// centralCountIs tells whether namespace has n central objects.
+func centralCountIs(ctx context.Context, tx *sql.Tx, namespace *models.Namespace, n int64) (bool, error) {
+	m, err := models.Objects(qm.Where(models.ObjectColumns.Namespace+" = ?", namespace.Name)).Count(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+	return n == m, nil
+}
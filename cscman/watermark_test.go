@@ -0,0 +1,123 @@
+package cscman
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/taskie/csc/cscman/models"
+)
+
+func TestWatermarkInconsistency(t *testing.T) {
+	synced := &models.Namespace{SyncedUpdatedAt: testTime, SyncedScanRunID: 3}
+	cases := []struct {
+		wm        watermark
+		namespace *models.Namespace
+		want      string
+	}{
+		{watermark{UpdatedAt: testTime, ScanRunID: 3, MaxScanRunID: 3}, synced, "csc.db has no deletion log"},
+		{watermark{UpdatedAt: testTime, ScanRunID: 3, MaxScanRunID: 3, Incremental: true},
+			&models.Namespace{SyncedUpdatedAt: time.Unix(0, 0).UTC()}, "no watermarks"},
+		{watermark{UpdatedAt: testTime, ScanRunID: 2, MaxScanRunID: 2, Incremental: true}, synced, "scan runs went back"},
+		{watermark{UpdatedAt: testTime.Add(-time.Second), ScanRunID: 3, MaxScanRunID: 3, Incremental: true}, synced, "updated_at went back"},
+		{watermark{UpdatedAt: testTime, ScanRunID: 3, MaxScanRunID: 3, Incremental: true}, synced, ""},
+		{watermark{UpdatedAt: testTime.Add(time.Hour), ScanRunID: 3, MaxScanRunID: 4, Incremental: true}, synced, ""},
+	}
+	for i, c := range cases {
+		if got := c.wm.inconsistency(c.namespace); got != c.want {
+			t.Errorf("case %d: inconsistency() = %q, want %q", i, got, c.want)
+		}
+	}
+}
+
+// corruptTestObject changes a central object behind the sync, which only a
+// full sync repairs.
+func corruptTestObject(t *testing.T, cm *CscMan, namespace string, path string) {
+	_, err := cm.db.ExecContext(context.Background(), "UPDATE objects SET sha256 = 'x' WHERE namespace = ? AND path = ?",
+		namespace, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestSyncedNamespace returns a namespace synced with a csc.db of a scan
+// run which found a, b and c. a is older than the watermarks and corrupted
+// in the namespace.
+func newTestSyncedNamespace(t *testing.T, cm *CscMan) (*models.Namespace, *testCSCDB) {
+	namespace := registerTestNamespace(t, cm, "foo")
+	c := newTestCSCDB(t)
+	c.addScanRun(1)
+	c.put("a", "1", testTime.Add(-time.Hour))
+	c.put("b", "1", testTime)
+	c.put("c", "1", testTime)
+	checkSyncResult(t, "first sync", syncTestNamespace(t, cm, namespace, c, false), SyncResult{Inserted: 3})
+	if !namespace.SyncedUpdatedAt.Equal(testTime) || namespace.SyncedScanRunID != 1 {
+		t.Errorf("watermarks after the first sync: %v, %d", namespace.SyncedUpdatedAt, namespace.SyncedScanRunID)
+	}
+	corruptTestObject(t, cm, "foo", "a")
+	return namespace, c
+}
+
+func TestSyncIncremental(t *testing.T) {
+	cm := newTestCscMan(t)
+	namespace, c := newTestSyncedNamespace(t, cm)
+
+	later := testTime.Add(time.Hour)
+	c.addScanRun(2)
+	c.remove("b", 2, later)
+	c.put("c", "2", later)
+	c.put("d", "1", later)
+	checkSyncResult(t, "incremental sync", syncTestNamespace(t, cm, namespace, c, false),
+		SyncResult{Inserted: 1, Updated: 1, Deleted: 1})
+	want := map[string]string{"a": "x", "c": "2", "d": "1"}
+	if got := listTestObjects(t, cm, "foo"); !reflect.DeepEqual(got, want) {
+		t.Errorf("objects: %v, want %v", got, want)
+	}
+	if !namespace.SyncedUpdatedAt.Equal(later) || namespace.SyncedScanRunID != 2 {
+		t.Errorf("watermarks: %v, %d", namespace.SyncedUpdatedAt, namespace.SyncedScanRunID)
+	}
+
+	// a deleted path which exists again is left to the updated objects
+	c.addScanRun(3)
+	c.put("b", "2", later.Add(time.Hour))
+	checkSyncResult(t, "sync of a path back", syncTestNamespace(t, cm, namespace, c, false), SyncResult{Inserted: 1})
+}
+
+func TestSyncFallsBackToFull(t *testing.T) {
+	later := testTime.Add(time.Hour)
+	cases := []struct {
+		name   string
+		change func(c *testCSCDB) *testCSCDB
+		want   SyncResult
+	}{
+		{"deletion not logged", func(c *testCSCDB) *testCSCDB {
+			c.addScanRun(2)
+			c.remove("b", 0, later)
+			c.put("c", "1", later)
+			return c
+		}, SyncResult{Updated: 1, Deleted: 1}},
+		{"scan runs went back", func(c *testCSCDB) *testCSCDB {
+			c = newTestCSCDB(t)
+			for _, path := range []string{"a", "b", "c"} {
+				c.put(path, "1", later)
+			}
+			return c
+		}, SyncResult{Updated: 1}},
+		{"updated_at went back", func(c *testCSCDB) *testCSCDB {
+			c.addScanRun(2)
+			c.exec("UPDATE objects SET updated_at = ?", testTime.Add(-2*time.Hour))
+			return c
+		}, SyncResult{Updated: 1}},
+	}
+	for _, tc := range cases {
+		cm := newTestCscMan(t)
+		namespace, c := newTestSyncedNamespace(t, cm)
+		c = tc.change(c)
+		// the corrupted object is repaired by the full sync
+		checkSyncResult(t, tc.name, syncTestNamespace(t, cm, namespace, c, false), tc.want)
+		if got := listTestObjects(t, cm, "foo")["a"]; got != "1" {
+			t.Errorf("%s: a is %q after the sync", tc.name, got)
+		}
+	}
+}
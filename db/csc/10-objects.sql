@@ -1,4 +1,4 @@
--- migrate +Up
+-- +migrate Up
 CREATE TABLE IF NOT EXISTS objects (
     id INTEGER PRIMARY KEY,
     path TEXT UNIQUE NOT NULL,
@@ -11,10 +11,10 @@ CREATE TABLE IF NOT EXISTS objects (
     updated_at DATETIME NOT NULL
 );
 
-CREATE INDEX objects_path ON objects (path); 
-CREATE INDEX objects_sha256_path ON objects (sha256, path);
-CREATE INDEX objects_mtime ON objects (mtime);
-CREATE INDEX objects_updated_at ON objects (updated_at);
+CREATE INDEX IF NOT EXISTS objects_path ON objects (path);
+CREATE INDEX IF NOT EXISTS objects_sha256_path ON objects (sha256, path);
+CREATE INDEX IF NOT EXISTS objects_mtime ON objects (mtime);
+CREATE INDEX IF NOT EXISTS objects_updated_at ON objects (updated_at);
 
--- migrate +Down
+-- +migrate Down
 DROP TABLE IF EXISTS objects;
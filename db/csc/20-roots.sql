@@ -0,0 +1,63 @@
+-- +migrate Up
+ALTER TABLE managements ADD COLUMN name TEXT NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS managements_name ON managements (name);
+
+CREATE TABLE objects_new (
+    id INTEGER PRIMARY KEY,
+    management_id INTEGER NOT NULL DEFAULT 0,
+    path TEXT NOT NULL,
+    type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    mtime DATETIME NOT NULL,
+    sha256 TEXT NOT NULL,
+    status TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE (management_id, path)
+);
+INSERT INTO objects_new (id, management_id, path, type, size, mtime, sha256, status, created_at, updated_at)
+    SELECT id, 0, path, type, size, mtime, sha256, status, created_at, updated_at FROM objects;
+DROP TABLE objects;
+ALTER TABLE objects_new RENAME TO objects;
+
+CREATE INDEX IF NOT EXISTS objects_path ON objects (path);
+CREATE INDEX IF NOT EXISTS objects_sha256_path ON objects (sha256, path);
+CREATE INDEX IF NOT EXISTS objects_mtime ON objects (mtime);
+CREATE INDEX IF NOT EXISTS objects_updated_at ON objects (updated_at);
+
+-- +migrate Down
+CREATE TABLE objects_old (
+    id INTEGER PRIMARY KEY,
+    path TEXT UNIQUE NOT NULL,
+    type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    mtime DATETIME NOT NULL,
+    sha256 TEXT NOT NULL,
+    status TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+INSERT INTO objects_old (id, path, type, size, mtime, sha256, status, created_at, updated_at)
+    SELECT id, path, type, size, mtime, sha256, status, created_at, updated_at FROM objects WHERE management_id = 0;
+DROP TABLE objects;
+ALTER TABLE objects_old RENAME TO objects;
+
+CREATE INDEX IF NOT EXISTS objects_path ON objects (path);
+CREATE INDEX IF NOT EXISTS objects_sha256_path ON objects (sha256, path);
+CREATE INDEX IF NOT EXISTS objects_mtime ON objects (mtime);
+CREATE INDEX IF NOT EXISTS objects_updated_at ON objects (updated_at);
+
+CREATE TABLE managements_old (
+    id INTEGER PRIMARY KEY,
+    base_path TEXT NOT NULL,
+    type TEXT NOT NULL,
+    mtime DATETIME NOT NULL,
+    status TEXT NOT NULL,
+    description TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+INSERT INTO managements_old (id, base_path, type, mtime, status, description, created_at, updated_at)
+    SELECT id, base_path, type, mtime, status, description, created_at, updated_at FROM managements;
+DROP TABLE managements;
+ALTER TABLE managements_old RENAME TO managements;
@@ -0,0 +1,34 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS scan_runs (
+    id INTEGER PRIMARY KEY,
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME,
+    roots TEXT NOT NULL,
+    seen INTEGER NOT NULL DEFAULT 0,
+    hashed INTEGER NOT NULL DEFAULT 0,
+    inserted INTEGER NOT NULL DEFAULT 0,
+    updated INTEGER NOT NULL DEFAULT 0,
+    deleted INTEGER NOT NULL DEFAULT 0,
+    errored INTEGER NOT NULL DEFAULT 0,
+    bytes_hashed INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL,
+    exit_status INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS scan_runs_started_at ON scan_runs (started_at);
+
+CREATE TABLE IF NOT EXISTS scan_errors (
+    id INTEGER PRIMARY KEY,
+    scan_run_id INTEGER NOT NULL,
+    management_id INTEGER NOT NULL,
+    path TEXT NOT NULL,
+    message TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS scan_errors_scan_run_id ON scan_errors (scan_run_id);
+CREATE INDEX IF NOT EXISTS scan_errors_path ON scan_errors (management_id, path);
+
+-- +migrate Down
+DROP TABLE IF EXISTS scan_errors;
+DROP TABLE IF EXISTS scan_runs;
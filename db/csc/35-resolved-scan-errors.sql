@@ -0,0 +1,22 @@
+-- +migrate Up
+-- set when a later scan finds the path readable or gone
+ALTER TABLE scan_errors ADD COLUMN resolved_at DATETIME;
+UPDATE scan_errors SET resolved_at = created_at
+    WHERE EXISTS (SELECT 1 FROM objects o WHERE o.management_id = scan_errors.management_id AND o.path = scan_errors.path
+        AND o.status <> 'unreadable');
+
+-- +migrate Down
+CREATE TABLE scan_errors_old (
+    id INTEGER PRIMARY KEY,
+    scan_run_id INTEGER NOT NULL,
+    management_id INTEGER NOT NULL,
+    path TEXT NOT NULL,
+    message TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+INSERT INTO scan_errors_old (id, scan_run_id, management_id, path, message, created_at)
+    SELECT id, scan_run_id, management_id, path, message, created_at FROM scan_errors;
+DROP TABLE scan_errors;
+ALTER TABLE scan_errors_old RENAME TO scan_errors;
+CREATE INDEX IF NOT EXISTS scan_errors_scan_run_id ON scan_errors (scan_run_id);
+CREATE INDEX IF NOT EXISTS scan_errors_path ON scan_errors (management_id, path);
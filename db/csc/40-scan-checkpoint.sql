@@ -0,0 +1,27 @@
+-- +migrate Up
+ALTER TABLE scan_runs ADD COLUMN checkpoint_walk INTEGER;
+ALTER TABLE scan_runs ADD COLUMN checkpoint_path TEXT;
+ALTER TABLE scan_runs ADD COLUMN resumed_from INTEGER;
+
+-- +migrate Down
+CREATE TABLE scan_runs_old (
+    id INTEGER PRIMARY KEY,
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME,
+    roots TEXT NOT NULL,
+    seen INTEGER NOT NULL DEFAULT 0,
+    hashed INTEGER NOT NULL DEFAULT 0,
+    inserted INTEGER NOT NULL DEFAULT 0,
+    updated INTEGER NOT NULL DEFAULT 0,
+    deleted INTEGER NOT NULL DEFAULT 0,
+    errored INTEGER NOT NULL DEFAULT 0,
+    bytes_hashed INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL,
+    exit_status INTEGER
+);
+INSERT INTO scan_runs_old
+    SELECT id, started_at, finished_at, roots, seen, hashed, inserted, updated, deleted, errored, bytes_hashed, status, exit_status
+    FROM scan_runs;
+DROP TABLE scan_runs;
+ALTER TABLE scan_runs_old RENAME TO scan_runs;
+CREATE INDEX IF NOT EXISTS scan_runs_started_at ON scan_runs (started_at);
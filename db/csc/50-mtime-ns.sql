@@ -0,0 +1,31 @@
+-- +migrate Up
+ALTER TABLE objects ADD COLUMN mtime_ns INTEGER NOT NULL DEFAULT 0;
+-- mtime is stored by go-sqlite3 as "2006-01-02 15:04:05.999999999-07:00";
+-- 0 means unknown and is filled in by the next scan
+UPDATE objects SET mtime_ns = CAST(strftime('%s', mtime) AS INTEGER) * 1000000000
+    + CAST(substr(CASE WHEN substr(mtime, 20, 1) = '.' THEN substr(mtime, 21, length(mtime) - 26) ELSE '' END || '000000000', 1, 9) AS INTEGER)
+    WHERE typeof(mtime) = 'text' AND mtime >= '1970' AND mtime < '2262';
+
+-- +migrate Down
+CREATE TABLE objects_old (
+    id INTEGER PRIMARY KEY,
+    management_id INTEGER NOT NULL DEFAULT 0,
+    path TEXT NOT NULL,
+    type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    mtime DATETIME NOT NULL,
+    sha256 TEXT NOT NULL,
+    status TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE (management_id, path)
+);
+INSERT INTO objects_old (id, management_id, path, type, size, mtime, sha256, status, created_at, updated_at)
+    SELECT id, management_id, path, type, size, mtime, sha256, status, created_at, updated_at FROM objects;
+DROP TABLE objects;
+ALTER TABLE objects_old RENAME TO objects;
+
+CREATE INDEX IF NOT EXISTS objects_path ON objects (path);
+CREATE INDEX IF NOT EXISTS objects_sha256_path ON objects (sha256, path);
+CREATE INDEX IF NOT EXISTS objects_mtime ON objects (mtime);
+CREATE INDEX IF NOT EXISTS objects_updated_at ON objects (updated_at);
@@ -0,0 +1,14 @@
+-- +migrate Up
+-- objects deleted by scans, for incremental syncs by cscman
+CREATE TABLE IF NOT EXISTS deletions (
+    id INTEGER PRIMARY KEY,
+    scan_run_id INTEGER NOT NULL,
+    management_id INTEGER NOT NULL,
+    path TEXT NOT NULL,
+    deleted_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS deletions_scan_run_id ON deletions (scan_run_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS deletions;
@@ -10,11 +10,10 @@ CREATE TABLE IF NOT EXISTS namespaces (
     description VARCHAR(1000) NOT NULL,
     created_at DATETIME NOT NULL,
     updated_at DATETIME NOT NULL,
-    PRIMARY KEY (name)
+    PRIMARY KEY (name),
+    INDEX namespaces_csc_db_mtime (csc_db_mtime),
+    INDEX namespaces_updated_at (updated_at)
 ) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
 
-CREATE INDEX namespaces_csc_db_mtime ON namespaces (csc_db_mtime);
-CREATE INDEX namespaces_updated_at ON namespaces (updated_at);
-
 -- +migrate Down
 DROP TABLE IF EXISTS namespaces;
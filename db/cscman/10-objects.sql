@@ -11,13 +11,12 @@ CREATE TABLE IF NOT EXISTS objects (
     created_at DATETIME NOT NULL,
     updated_at DATETIME NOT NULL,
     UNIQUE (namespace, path),
-    PRIMARY KEY (id)
+    PRIMARY KEY (id),
+    INDEX objects_path (path),
+    INDEX objects_sha256_path (sha256, path),
+    INDEX objects_mtime (mtime),
+    INDEX objects_updated_at (updated_at)
 ) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
 
-CREATE INDEX objects_path ON objects (path); 
-CREATE INDEX objects_sha256_path ON objects (sha256, path);
-CREATE INDEX objects_mtime ON objects (mtime);
-CREATE INDEX objects_updated_at ON objects (updated_at);
-
 -- +migrate Down
 DROP TABLE IF EXISTS objects;
@@ -0,0 +1,7 @@
+-- +migrate Up
+-- DATETIME drops the fraction of seconds; mtime_ns keeps the exact value
+-- and 0 means unknown until the next sync
+ALTER TABLE objects ADD COLUMN mtime_ns BIGINT NOT NULL DEFAULT 0 AFTER mtime;
+
+-- +migrate Down
+ALTER TABLE objects DROP COLUMN mtime_ns;
@@ -0,0 +1,6 @@
+-- +migrate Up
+-- the ETag of the csc.db last fetched over HTTP for conditional requests
+ALTER TABLE namespaces ADD COLUMN etag VARCHAR(200) NOT NULL DEFAULT '' AFTER csc_db_sha256;
+
+-- +migrate Down
+ALTER TABLE namespaces DROP COLUMN etag;
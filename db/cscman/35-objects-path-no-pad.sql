@@ -0,0 +1,8 @@
+-- +migrate Up
+-- utf8mb4_bin pads paths with spaces when comparing them, so "a " is a
+-- duplicate of "a" and "a\t" sorts before "a". Syncs merge the objects
+-- ordered by path and need the byte order of csc.db.
+ALTER TABLE objects MODIFY COLUMN path VARCHAR(500) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_bin NOT NULL;
+
+-- +migrate Down
+ALTER TABLE objects MODIFY COLUMN path VARCHAR(500) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL;
@@ -0,0 +1,9 @@
+-- +migrate Up
+-- the highest updated_at of objects and the last finished scan run of the
+-- csc.db ingested by the last sync
+ALTER TABLE namespaces
+    ADD COLUMN synced_updated_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00' AFTER etag,
+    ADD COLUMN synced_scan_run_id BIGINT NOT NULL DEFAULT 0 AFTER synced_updated_at;
+
+-- +migrate Down
+ALTER TABLE namespaces DROP COLUMN synced_scan_run_id, DROP COLUMN synced_updated_at;
@@ -0,0 +1,6 @@
+-- +migrate Up
+-- the error of the last sync if it failed
+ALTER TABLE namespaces ADD COLUMN last_error VARCHAR(1000) NOT NULL DEFAULT '' AFTER status;
+
+-- +migrate Down
+ALTER TABLE namespaces DROP COLUMN last_error;
@@ -0,0 +1,6 @@
+-- +migrate Up
+-- the time of the last successful sync, whether or not anything changed
+ALTER TABLE namespaces ADD COLUMN synced_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00' AFTER synced_scan_run_id;
+
+-- +migrate Down
+ALTER TABLE namespaces DROP COLUMN synced_at;
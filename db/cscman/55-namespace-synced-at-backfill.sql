@@ -0,0 +1,7 @@
+-- +migrate Up
+-- namespaces synced before synced_at was added were last synced when they
+-- were updated
+UPDATE namespaces SET synced_at = updated_at WHERE status = 'ok';
+
+-- +migrate Down
+UPDATE namespaces SET synced_at = '1970-01-01 00:00:00';
@@ -0,0 +1,19 @@
+-- +migrate Up
+-- API tokens of cscman serve; only the SHA-256 of a token is stored.
+-- scopes and namespaces are comma-separated lists, and a namespace may be a
+-- glob such as "*"
+CREATE TABLE IF NOT EXISTS tokens (
+    id INTEGER AUTO_INCREMENT,
+    name VARCHAR(100) NOT NULL,
+    token_sha256 CHAR(64) NOT NULL,
+    scopes VARCHAR(100) NOT NULL,
+    namespaces VARCHAR(1000) NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE (name),
+    UNIQUE (token_sha256),
+    PRIMARY KEY (id)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- +migrate Down
+DROP TABLE IF EXISTS tokens;
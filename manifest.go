@@ -0,0 +1,122 @@
+package csc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const (
+	ManifestFormatGNU = "gnu"
+	ManifestFormatBSD = "bsd"
+)
+
+// ManifestEntry is a line of a SHA256SUMS style manifest.
+type ManifestEntry struct {
+	Path   string
+	Sha256 string
+}
+
+var (
+	gnuManifestLine = regexp.MustCompile(`^(\\?)([0-9a-fA-F]{64}) [ *](.*)$`)
+	bsdManifestLine = regexp.MustCompile(`^(\\?)SHA256 ?\((.*)\) ?= ([0-9a-fA-F]{64})$`)
+)
+
+func unescapeManifestPath(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			case 'r':
+				b.WriteByte('\r')
+				i++
+				continue
+			case '\\':
+				b.WriteByte('\\')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func escapeManifestPath(s string) (string, bool) {
+	if !strings.ContainsAny(s, "\\\n\r") {
+		return s, false
+	}
+	r := strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r")
+	return r.Replace(s), true
+}
+
+// ParseManifestLine parses a line in either GNU coreutils ("HEX  PATH") or
+// BSD ("SHA256 (PATH) = HEX") format.
+func ParseManifestLine(line string) (*ManifestEntry, error) {
+	line = strings.TrimSuffix(line, "\r")
+	if m := bsdManifestLine.FindStringSubmatch(line); m != nil {
+		path := m[2]
+		if m[1] != "" {
+			path = unescapeManifestPath(path)
+		}
+		return &ManifestEntry{Path: path, Sha256: strings.ToLower(m[3])}, nil
+	}
+	if m := gnuManifestLine.FindStringSubmatch(line); m != nil {
+		path := m[3]
+		if m[1] != "" {
+			path = unescapeManifestPath(path)
+		}
+		return &ManifestEntry{Path: path, Sha256: strings.ToLower(m[2])}, nil
+	}
+	return nil, fmt.Errorf("invalid manifest line: %q", line)
+}
+
+// ParseManifest reads all entries of a manifest. Blank lines and lines
+// starting with '#' are ignored.
+func ParseManifest(r io.Reader) ([]*ManifestEntry, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	entries := make([]*ManifestEntry, 0)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		e, err := ParseManifestLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		entries = append(entries, e)
+	}
+	err := sc.Err()
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// WriteManifestEntry writes an entry in the given format. Paths containing
+// backslashes or newlines are escaped the same way as coreutils does.
+func WriteManifestEntry(w io.Writer, format string, e *ManifestEntry) error {
+	path, escaped := escapeManifestPath(e.Path)
+	prefix := ""
+	if escaped {
+		prefix = "\\"
+	}
+	var err error
+	switch format {
+	case ManifestFormatGNU, "":
+		_, err = fmt.Fprintf(w, "%s%s  %s\n", prefix, e.Sha256, path)
+	case ManifestFormatBSD:
+		_, err = fmt.Fprintf(w, "%sSHA256 (%s) = %s\n", prefix, path, e.Sha256)
+	default:
+		err = fmt.Errorf("unknown manifest format: %s", format)
+	}
+	return err
+}
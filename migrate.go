@@ -0,0 +1,364 @@
+package csc
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed db/csc/*.sql
+var cscMigrationFS embed.FS
+
+//go:embed db/cscman/*.sql
+var cscManMigrationFS embed.FS
+
+const (
+	migrateUpMarker   = "-- +migrate Up"
+	migrateDownMarker = "-- +migrate Down"
+)
+
+// MigrationTableName is the table which records applied migrations.
+const MigrationTableName = "schema_migrations"
+
+// Migration is a versioned schema change loaded from a "NN-name.sql" file
+// with "-- +migrate Up" and "-- +migrate Down" sections.
+type Migration struct {
+	Version int
+	Name    string
+	Up      []string
+	Down    []string
+}
+
+// MigrationStatus tells whether a migration has been applied.
+type MigrationStatus struct {
+	*Migration
+	AppliedAt *time.Time
+}
+
+// splitStatements splits SQL into statements terminated by semicolons.
+// Semicolons in quotes and comments and between BEGIN and END of a trigger
+// don't terminate a statement. Comments are dropped.
+func splitStatements(src string) []string {
+	stmts := make([]string, 0)
+	var buf strings.Builder
+	// word is the keyword being read, trigger tells whether the statement
+	// creates a trigger, and depth is the nesting of BEGIN or CASE and END
+	// in it.
+	var word strings.Builder
+	trigger := false
+	depth := 0
+	endWord := func() {
+		switch strings.ToUpper(word.String()) {
+		case "TRIGGER":
+			trigger = trigger || depth == 0
+		case "BEGIN", "CASE":
+			if trigger {
+				depth++
+			}
+		case "END":
+			if depth > 0 {
+				depth--
+			}
+		}
+		word.Reset()
+	}
+	flush := func() {
+		if stmt := strings.TrimSpace(buf.String()); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+		buf.Reset()
+		trigger = false
+		depth = 0
+	}
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '_' || '0' <= c && c <= '9' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z':
+			word.WriteByte(c)
+			buf.WriteByte(c)
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			// a quote in quotes is escaped by doubling it
+			j := i + 1
+			for j < len(src) && (src[j] != c || j+1 < len(src) && src[j+1] == c) {
+				if src[j] == c {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				j = len(src) - 1
+			}
+			endWord()
+			buf.WriteString(src[i : j+1])
+			i = j
+			continue
+		}
+		endWord()
+		switch {
+		case strings.HasPrefix(src[i:], "--"):
+			j := strings.IndexByte(src[i:], '\n')
+			if j < 0 {
+				i = len(src)
+			} else {
+				i += j - 1
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			j := strings.Index(src[i+2:], "*/")
+			if j < 0 {
+				i = len(src)
+			} else {
+				i += j + 3
+			}
+		case c == ';' && depth == 0:
+			buf.WriteByte(c)
+			flush()
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	endWord()
+	flush()
+	return stmts
+}
+
+// ParseMigration parses a migration file. The version is taken from the
+// numeric prefix of its name.
+func ParseMigration(name string, src string) (*Migration, error) {
+	base := strings.TrimSuffix(path.Base(name), ".sql")
+	i := strings.IndexByte(base, '-')
+	if i < 0 {
+		return nil, fmt.Errorf("migration name must be NN-name.sql: %s", name)
+	}
+	version, err := strconv.Atoi(base[:i])
+	if err != nil {
+		return nil, fmt.Errorf("invalid migration version: %s", name)
+	}
+	up := strings.Index(src, migrateUpMarker)
+	if up < 0 {
+		return nil, fmt.Errorf("%q not found: %s", migrateUpMarker, name)
+	}
+	down := strings.Index(src, migrateDownMarker)
+	m := &Migration{Version: version, Name: base[i+1:]}
+	if down < 0 {
+		m.Up = splitStatements(src[up+len(migrateUpMarker):])
+	} else if down > up {
+		m.Up = splitStatements(src[up+len(migrateUpMarker) : down])
+		m.Down = splitStatements(src[down+len(migrateDownMarker):])
+	} else {
+		m.Down = splitStatements(src[down+len(migrateDownMarker) : up])
+		m.Up = splitStatements(src[up+len(migrateUpMarker):])
+	}
+	return m, nil
+}
+
+// LoadMigrations loads all *.sql files in dir ordered by version.
+func LoadMigrations(fsys fs.FS, dir string) ([]*Migration, error) {
+	names, err := fs.Glob(fsys, path.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	ms := make([]*Migration, 0, len(names))
+	seen := make(map[int]string)
+	for _, name := range names {
+		bs, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		m, err := ParseMigration(name, string(bs))
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := seen[m.Version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d: %s, %s", m.Version, other, name)
+		}
+		seen[m.Version] = name
+		ms = append(ms, m)
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+	return ms, nil
+}
+
+// CscMigrations returns the migrations of csc.db (SQLite).
+func CscMigrations() ([]*Migration, error) {
+	return LoadMigrations(cscMigrationFS, "db/csc")
+}
+
+// CscManMigrations returns the migrations of the cscman database (MySQL).
+// MySQL commits each DDL statement on its own, so each of them has a single
+// statement which is either applied and recorded or not applied at all.
+func CscManMigrations() ([]*Migration, error) {
+	return LoadMigrations(cscManMigrationFS, "db/cscman")
+}
+
+// Migrator applies migrations to a database and records them in
+// MigrationTableName.
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+}
+
+func NewMigrator(db *sql.DB, migrations []*Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+MigrationTableName+` (
+    version BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    applied_at DATETIME NOT NULL,
+    PRIMARY KEY (version)
+)`)
+	return err
+}
+
+// Applied returns the applied versions and when they were applied.
+func (m *Migrator) Applied(ctx context.Context) (map[int]time.Time, error) {
+	err := m.ensureTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM `+MigrationTableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		err = rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// Status returns every known migration with its applied time, if any.
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sts := make([]*MigrationStatus, len(m.migrations))
+	for i, mig := range m.migrations {
+		sts[i] = &MigrationStatus{Migration: mig}
+		if t, ok := applied[mig.Version]; ok {
+			sts[i].AppliedAt = &t
+		}
+	}
+	return sts, nil
+}
+
+// Pending returns the migrations which are not applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]*Migration, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]*Migration, 0)
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// run runs stmts of mig and record in a transaction. Only SQLite rolls back
+// DDL statements with it; MySQL commits each of them implicitly, and a
+// migration which fails after one of them stays partly applied.
+func (m *Migrator) run(ctx context.Context, mig *Migration, stmts []string, record string, args ...interface{}) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, stmt := range stmts {
+		_, err = tx.ExecContext(ctx, stmt)
+		if err != nil {
+			return fmt.Errorf("migration %02d-%s: %v", mig.Version, mig.Name, err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, record, args...)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Up applies all pending migrations in order and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, mig := range pending {
+		err = m.run(ctx, mig, mig.Up,
+			`INSERT INTO `+MigrationTableName+` (version, name, applied_at) VALUES (?, ?, ?)`,
+			mig.Version, mig.Name, time.Now().UTC())
+		if err != nil {
+			return pending[:i], err
+		}
+	}
+	return pending, nil
+}
+
+// Down reverts the last steps applied migrations and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reverted := make([]*Migration, 0)
+	for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		err = m.run(ctx, mig, mig.Down,
+			`DELETE FROM `+MigrationTableName+` WHERE version = ?`, mig.Version)
+		if err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, mig)
+	}
+	return reverted, nil
+}
+
+// MarkApplied records migrations as applied without running them. It is
+// used to adopt databases whose schema was created by hand.
+func (m *Migrator) MarkApplied(ctx context.Context, migs []*Migration) error {
+	err := m.ensureTable(ctx)
+	if err != nil {
+		return err
+	}
+	for _, mig := range migs {
+		_, err = m.db.ExecContext(ctx,
+			`INSERT INTO `+MigrationTableName+` (version, name, applied_at) VALUES (?, ?, ?)`,
+			mig.Version, mig.Name, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateCscDB brings csc.db up to date.
+func MigrateCscDB(ctx context.Context, db *sql.DB) error {
+	ms, err := CscMigrations()
+	if err != nil {
+		return err
+	}
+	_, err = NewMigrator(db, ms).Up(ctx)
+	return err
+}
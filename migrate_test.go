@@ -0,0 +1,140 @@
+package csc
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSplitStatements(t *testing.T) {
+	cases := []struct {
+		src  string
+		want []string
+	}{
+		{"CREATE TABLE a (x INTEGER);\nCREATE INDEX a_x ON a (x);\n",
+			[]string{"CREATE TABLE a (x INTEGER);", "CREATE INDEX a_x ON a (x);"}},
+		{"-- a comment; with a semicolon\nDROP TABLE a; -- trailing\n",
+			[]string{"DROP TABLE a;"}},
+		{"INSERT INTO a VALUES ('x;y'), ('it''s;'); SELECT \"a;b\", `c;d`;",
+			[]string{"INSERT INTO a VALUES ('x;y'), ('it''s;');", "SELECT \"a;b\", `c;d`;"}},
+		{"UPDATE a SET x = 1 /* ; */;\nSELECT '-- not a comment';",
+			[]string{"UPDATE a SET x = 1 ;", "SELECT '-- not a comment';"}},
+		{"CREATE TRIGGER t AFTER DELETE ON a BEGIN\n  INSERT INTO b VALUES (old.x);\n  UPDATE c SET n = CASE WHEN n > 0 THEN n - 1 ELSE 0 END;\nEND;\nDROP TABLE d;",
+			[]string{"CREATE TRIGGER t AFTER DELETE ON a BEGIN\n  INSERT INTO b VALUES (old.x);\n  UPDATE c SET n = CASE WHEN n > 0 THEN n - 1 ELSE 0 END;\nEND;", "DROP TABLE d;"}},
+		{"BEGIN;\nSELECT 1;\nCOMMIT;", []string{"BEGIN;", "SELECT 1;", "COMMIT;"}},
+		{"SELECT 1", []string{"SELECT 1"}},
+		{"\n-- only a comment\n", []string{}},
+	}
+	for _, c := range cases {
+		got := splitStatements(c.src)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("splitStatements(%q) = %q, want %q", c.src, got, c.want)
+		}
+	}
+}
+
+func TestParseMigration(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want *Migration
+	}{
+		{"db/csc/10-objects.sql", "-- +migrate Up\nCREATE TABLE a (x INTEGER);\n\n-- +migrate Down\nDROP TABLE a;\n",
+			&Migration{Version: 10, Name: "objects", Up: []string{"CREATE TABLE a (x INTEGER);"}, Down: []string{"DROP TABLE a;"}}},
+		{"20-up-only.sql", "-- +migrate Up\nDROP TABLE a;\n",
+			&Migration{Version: 20, Name: "up-only", Up: []string{"DROP TABLE a;"}, Down: []string{}}},
+		{"30-down-first.sql", "-- +migrate Down\nDROP TABLE a;\n-- +migrate Up\nCREATE TABLE a (x INTEGER);\n",
+			&Migration{Version: 30, Name: "down-first", Up: []string{"CREATE TABLE a (x INTEGER);"}, Down: []string{"DROP TABLE a;"}}},
+	}
+	for _, c := range cases {
+		m, err := ParseMigration(c.name, c.src)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if m.Down == nil {
+			m.Down = []string{}
+		}
+		if !reflect.DeepEqual(m, c.want) {
+			t.Errorf("%s: %+v, want %+v", c.name, m, c.want)
+		}
+	}
+	for _, name := range []string{"objects.sql", "x1-objects.sql"} {
+		_, err := ParseMigration(name, "-- +migrate Up\n")
+		if err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+	_, err := ParseMigration("10-objects.sql", "CREATE TABLE a (x INTEGER);")
+	if err == nil {
+		t.Error("a migration without the Up marker is accepted")
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for _, load := range []func() ([]*Migration, error){CscMigrations, CscManMigrations} {
+		ms, err := load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, m := range ms {
+			if len(m.Up) == 0 || len(m.Down) == 0 {
+				t.Errorf("%d-%s lacks Up or Down", m.Version, m.Name)
+			}
+			if i > 0 && ms[i-1].Version >= m.Version {
+				t.Errorf("%d-%s is out of order", m.Version, m.Name)
+			}
+		}
+	}
+}
+
+// TestCscManMigrationsHaveOneStatement checks that no cscman migration can
+// be left partly applied by MySQL.
+func TestCscManMigrationsHaveOneStatement(t *testing.T) {
+	ms, err := CscManMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range ms {
+		if len(m.Up) != 1 || len(m.Down) != 1 {
+			t.Errorf("%d-%s has %d Up and %d Down statements, want 1 each", m.Version, m.Name, len(m.Up), len(m.Down))
+		}
+	}
+}
+
+// TestCscMigrationsDownAndUp reverts the last n migrations and applies them
+// again for every n.
+func TestCscMigrationsDownAndUp(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "csc.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ms, err := CscMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMigrator(db, ms)
+	_, err = m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 1; n <= len(ms); n++ {
+		reverted, err := m.Down(ctx, n)
+		if err != nil {
+			t.Fatalf("down %d: %v", n, err)
+		}
+		if len(reverted) != n {
+			t.Errorf("down %d reverted %d migrations", n, len(reverted))
+		}
+		_, err = m.Up(ctx)
+		if err != nil {
+			t.Fatalf("up after down %d: %v", n, err)
+		}
+	}
+}
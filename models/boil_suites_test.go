@@ -12,51 +12,64 @@ import "testing"
 // It does NOT run each operation group in parallel.
 // Separating the tests thusly grants avoidance of Postgres deadlocks.
 func TestParent(t *testing.T) {
+	t.Run("Managements", testManagements)
 	t.Run("Objects", testObjects)
 }
 
 func TestDelete(t *testing.T) {
+	t.Run("Managements", testManagementsDelete)
 	t.Run("Objects", testObjectsDelete)
 }
 
 func TestQueryDeleteAll(t *testing.T) {
+	t.Run("Managements", testManagementsQueryDeleteAll)
 	t.Run("Objects", testObjectsQueryDeleteAll)
 }
 
 func TestSliceDeleteAll(t *testing.T) {
+	t.Run("Managements", testManagementsSliceDeleteAll)
 	t.Run("Objects", testObjectsSliceDeleteAll)
 }
 
 func TestExists(t *testing.T) {
+	t.Run("Managements", testManagementsExists)
 	t.Run("Objects", testObjectsExists)
 }
 
 func TestFind(t *testing.T) {
+	t.Run("Managements", testManagementsFind)
 	t.Run("Objects", testObjectsFind)
 }
 
 func TestBind(t *testing.T) {
+	t.Run("Managements", testManagementsBind)
 	t.Run("Objects", testObjectsBind)
 }
 
 func TestOne(t *testing.T) {
+	t.Run("Managements", testManagementsOne)
 	t.Run("Objects", testObjectsOne)
 }
 
 func TestAll(t *testing.T) {
+	t.Run("Managements", testManagementsAll)
 	t.Run("Objects", testObjectsAll)
 }
 
 func TestCount(t *testing.T) {
+	t.Run("Managements", testManagementsCount)
 	t.Run("Objects", testObjectsCount)
 }
 
 func TestHooks(t *testing.T) {
+	t.Run("Managements", testManagementsHooks)
 	t.Run("Objects", testObjectsHooks)
 }
 
 func TestInsert(t *testing.T) {
+	t.Run("Managements", testManagementsInsert)
 	t.Run("Objects", testObjectsInsert)
+	t.Run("Managements", testManagementsInsertWhitelist)
 	t.Run("Objects", testObjectsInsertWhitelist)
 }
 
@@ -101,21 +114,26 @@ func TestToManySet(t *testing.T) {}
 func TestToManyRemove(t *testing.T) {}
 
 func TestReload(t *testing.T) {
+	t.Run("Managements", testManagementsReload)
 	t.Run("Objects", testObjectsReload)
 }
 
 func TestReloadAll(t *testing.T) {
+	t.Run("Managements", testManagementsReloadAll)
 	t.Run("Objects", testObjectsReloadAll)
 }
 
 func TestSelect(t *testing.T) {
+	t.Run("Managements", testManagementsSelect)
 	t.Run("Objects", testObjectsSelect)
 }
 
 func TestUpdate(t *testing.T) {
+	t.Run("Managements", testManagementsUpdate)
 	t.Run("Objects", testObjectsUpdate)
 }
 
 func TestSliceUpdateAll(t *testing.T) {
+	t.Run("Managements", testManagementsSliceUpdateAll)
 	t.Run("Objects", testObjectsSliceUpdateAll)
 }
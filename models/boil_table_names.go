@@ -4,7 +4,9 @@
 package models
 
 var TableNames = struct {
-	Objects string
+	Managements string
+	Objects     string
 }{
-	Objects: "objects",
+	Managements: "managements",
+	Objects:     "objects",
 }
@@ -0,0 +1,684 @@
+// Code generated by SQLBoiler 3.5.0 (https://github.com/volatiletech/sqlboiler). DO NOT EDIT.
+// This file is meant to be re-generated in place and/or deleted at any time.
+
+package models
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/volatiletech/sqlboiler/boil"
+	"github.com/volatiletech/sqlboiler/queries"
+	"github.com/volatiletech/sqlboiler/randomize"
+	"github.com/volatiletech/sqlboiler/strmangle"
+)
+
+var (
+	// Relationships sometimes use the reflection helper queries.Equal/queries.Assign
+	// so force a package dependency in case they don't.
+	_ = queries.Equal
+)
+
+func testManagements(t *testing.T) {
+	t.Parallel()
+
+	query := Managements()
+
+	if query.Query == nil {
+		t.Error("expected a query, got nothing")
+	}
+}
+
+func testManagementsDelete(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Management{}
+	if err = randomize.Struct(seed, o, managementDBTypes, true, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	if rowsAff, err := o.Delete(ctx, tx); err != nil {
+		t.Error(err)
+	} else if rowsAff != 1 {
+		t.Error("should only have deleted one row, but affected:", rowsAff)
+	}
+
+	count, err := Managements().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 0 {
+		t.Error("want zero records, got:", count)
+	}
+}
+
+func testManagementsQueryDeleteAll(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Management{}
+	if err = randomize.Struct(seed, o, managementDBTypes, true, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	if rowsAff, err := Managements().DeleteAll(ctx, tx); err != nil {
+		t.Error(err)
+	} else if rowsAff != 1 {
+		t.Error("should only have deleted one row, but affected:", rowsAff)
+	}
+
+	count, err := Managements().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 0 {
+		t.Error("want zero records, got:", count)
+	}
+}
+
+func testManagementsSliceDeleteAll(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Management{}
+	if err = randomize.Struct(seed, o, managementDBTypes, true, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	slice := ManagementSlice{o}
+
+	if rowsAff, err := slice.DeleteAll(ctx, tx); err != nil {
+		t.Error(err)
+	} else if rowsAff != 1 {
+		t.Error("should only have deleted one row, but affected:", rowsAff)
+	}
+
+	count, err := Managements().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 0 {
+		t.Error("want zero records, got:", count)
+	}
+}
+
+func testManagementsExists(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Management{}
+	if err = randomize.Struct(seed, o, managementDBTypes, true, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	e, err := ManagementExists(ctx, tx, o.ID)
+	if err != nil {
+		t.Errorf("Unable to check if Management exists: %s", err)
+	}
+	if !e {
+		t.Errorf("Expected ManagementExists to return true, but got false.")
+	}
+}
+
+func testManagementsFind(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Management{}
+	if err = randomize.Struct(seed, o, managementDBTypes, true, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	managementFound, err := FindManagement(ctx, tx, o.ID)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if managementFound == nil {
+		t.Error("want a record, got nil")
+	}
+}
+
+func testManagementsBind(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Management{}
+	if err = randomize.Struct(seed, o, managementDBTypes, true, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	if err = Managements().Bind(ctx, tx, o); err != nil {
+		t.Error(err)
+	}
+}
+
+func testManagementsOne(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Management{}
+	if err = randomize.Struct(seed, o, managementDBTypes, true, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	if x, err := Managements().One(ctx, tx); err != nil {
+		t.Error(err)
+	} else if x == nil {
+		t.Error("expected to get a non nil record")
+	}
+}
+
+func testManagementsAll(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	managementOne := &Management{}
+	managementTwo := &Management{}
+	if err = randomize.Struct(seed, managementOne, managementDBTypes, false, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+	if err = randomize.Struct(seed, managementTwo, managementDBTypes, false, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = managementOne.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+	if err = managementTwo.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	slice, err := Managements().All(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(slice) != 2 {
+		t.Error("want 2 records, got:", len(slice))
+	}
+}
+
+func testManagementsCount(t *testing.T) {
+	t.Parallel()
+
+	var err error
+	seed := randomize.NewSeed()
+	managementOne := &Management{}
+	managementTwo := &Management{}
+	if err = randomize.Struct(seed, managementOne, managementDBTypes, false, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+	if err = randomize.Struct(seed, managementTwo, managementDBTypes, false, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = managementOne.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+	if err = managementTwo.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	count, err := Managements().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 2 {
+		t.Error("want 2 records, got:", count)
+	}
+}
+
+func managementBeforeInsertHook(ctx context.Context, e boil.ContextExecutor, o *Management) error {
+	*o = Management{}
+	return nil
+}
+
+func managementAfterInsertHook(ctx context.Context, e boil.ContextExecutor, o *Management) error {
+	*o = Management{}
+	return nil
+}
+
+func managementAfterSelectHook(ctx context.Context, e boil.ContextExecutor, o *Management) error {
+	*o = Management{}
+	return nil
+}
+
+func managementBeforeUpdateHook(ctx context.Context, e boil.ContextExecutor, o *Management) error {
+	*o = Management{}
+	return nil
+}
+
+func managementAfterUpdateHook(ctx context.Context, e boil.ContextExecutor, o *Management) error {
+	*o = Management{}
+	return nil
+}
+
+func managementBeforeDeleteHook(ctx context.Context, e boil.ContextExecutor, o *Management) error {
+	*o = Management{}
+	return nil
+}
+
+func managementAfterDeleteHook(ctx context.Context, e boil.ContextExecutor, o *Management) error {
+	*o = Management{}
+	return nil
+}
+
+func managementBeforeUpsertHook(ctx context.Context, e boil.ContextExecutor, o *Management) error {
+	*o = Management{}
+	return nil
+}
+
+func managementAfterUpsertHook(ctx context.Context, e boil.ContextExecutor, o *Management) error {
+	*o = Management{}
+	return nil
+}
+
+func testManagementsHooks(t *testing.T) {
+	t.Parallel()
+
+	var err error
+
+	ctx := context.Background()
+	empty := &Management{}
+	o := &Management{}
+
+	seed := randomize.NewSeed()
+	if err = randomize.Struct(seed, o, managementDBTypes, false); err != nil {
+		t.Errorf("Unable to randomize Management object: %s", err)
+	}
+
+	AddManagementHook(boil.BeforeInsertHook, managementBeforeInsertHook)
+	if err = o.doBeforeInsertHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doBeforeInsertHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected BeforeInsertHook function to empty object, but got: %#v", o)
+	}
+	managementBeforeInsertHooks = []ManagementHook{}
+
+	AddManagementHook(boil.AfterInsertHook, managementAfterInsertHook)
+	if err = o.doAfterInsertHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doAfterInsertHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected AfterInsertHook function to empty object, but got: %#v", o)
+	}
+	managementAfterInsertHooks = []ManagementHook{}
+
+	AddManagementHook(boil.AfterSelectHook, managementAfterSelectHook)
+	if err = o.doAfterSelectHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doAfterSelectHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected AfterSelectHook function to empty object, but got: %#v", o)
+	}
+	managementAfterSelectHooks = []ManagementHook{}
+
+	AddManagementHook(boil.BeforeUpdateHook, managementBeforeUpdateHook)
+	if err = o.doBeforeUpdateHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doBeforeUpdateHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected BeforeUpdateHook function to empty object, but got: %#v", o)
+	}
+	managementBeforeUpdateHooks = []ManagementHook{}
+
+	AddManagementHook(boil.AfterUpdateHook, managementAfterUpdateHook)
+	if err = o.doAfterUpdateHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doAfterUpdateHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected AfterUpdateHook function to empty object, but got: %#v", o)
+	}
+	managementAfterUpdateHooks = []ManagementHook{}
+
+	AddManagementHook(boil.BeforeDeleteHook, managementBeforeDeleteHook)
+	if err = o.doBeforeDeleteHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doBeforeDeleteHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected BeforeDeleteHook function to empty object, but got: %#v", o)
+	}
+	managementBeforeDeleteHooks = []ManagementHook{}
+
+	AddManagementHook(boil.AfterDeleteHook, managementAfterDeleteHook)
+	if err = o.doAfterDeleteHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doAfterDeleteHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected AfterDeleteHook function to empty object, but got: %#v", o)
+	}
+	managementAfterDeleteHooks = []ManagementHook{}
+
+	AddManagementHook(boil.BeforeUpsertHook, managementBeforeUpsertHook)
+	if err = o.doBeforeUpsertHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doBeforeUpsertHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected BeforeUpsertHook function to empty object, but got: %#v", o)
+	}
+	managementBeforeUpsertHooks = []ManagementHook{}
+
+	AddManagementHook(boil.AfterUpsertHook, managementAfterUpsertHook)
+	if err = o.doAfterUpsertHooks(ctx, nil); err != nil {
+		t.Errorf("Unable to execute doAfterUpsertHooks: %s", err)
+	}
+	if !reflect.DeepEqual(o, empty) {
+		t.Errorf("Expected AfterUpsertHook function to empty object, but got: %#v", o)
+	}
+	managementAfterUpsertHooks = []ManagementHook{}
+}
+
+func testManagementsInsert(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Management{}
+	if err = randomize.Struct(seed, o, managementDBTypes, true, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	count, err := Managements().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 1 {
+		t.Error("want one record, got:", count)
+	}
+}
+
+func testManagementsInsertWhitelist(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Management{}
+	if err = randomize.Struct(seed, o, managementDBTypes, true); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Whitelist(managementColumnsWithoutDefault...)); err != nil {
+		t.Error(err)
+	}
+
+	count, err := Managements().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 1 {
+		t.Error("want one record, got:", count)
+	}
+}
+
+func testManagementsReload(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Management{}
+	if err = randomize.Struct(seed, o, managementDBTypes, true, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	if err = o.Reload(ctx, tx); err != nil {
+		t.Error(err)
+	}
+}
+
+func testManagementsReloadAll(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Management{}
+	if err = randomize.Struct(seed, o, managementDBTypes, true, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	slice := ManagementSlice{o}
+
+	if err = slice.ReloadAll(ctx, tx); err != nil {
+		t.Error(err)
+	}
+}
+
+func testManagementsSelect(t *testing.T) {
+	t.Parallel()
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Management{}
+	if err = randomize.Struct(seed, o, managementDBTypes, true, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	slice, err := Managements().All(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(slice) != 1 {
+		t.Error("want one record, got:", len(slice))
+	}
+}
+
+var (
+	managementDBTypes = map[string]string{`ID`: `INTEGER`, `BasePath`: `TEXT`, `Type`: `TEXT`, `Mtime`: `DATETIME`, `Status`: `TEXT`, `Description`: `TEXT`, `CreatedAt`: `DATETIME`, `UpdatedAt`: `DATETIME`, `Name`: `TEXT`}
+	_                 = bytes.MinRead
+)
+
+func testManagementsUpdate(t *testing.T) {
+	t.Parallel()
+
+	if 0 == len(managementPrimaryKeyColumns) {
+		t.Skip("Skipping table with no primary key columns")
+	}
+	if len(managementAllColumns) == len(managementPrimaryKeyColumns) {
+		t.Skip("Skipping table with only primary key columns")
+	}
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Management{}
+	if err = randomize.Struct(seed, o, managementDBTypes, true, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	count, err := Managements().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 1 {
+		t.Error("want one record, got:", count)
+	}
+
+	if err = randomize.Struct(seed, o, managementDBTypes, true, managementPrimaryKeyColumns...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	if rowsAff, err := o.Update(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	} else if rowsAff != 1 {
+		t.Error("should only affect one row but affected", rowsAff)
+	}
+}
+
+func testManagementsSliceUpdateAll(t *testing.T) {
+	t.Parallel()
+
+	if len(managementAllColumns) == len(managementPrimaryKeyColumns) {
+		t.Skip("Skipping table with only primary key columns")
+	}
+
+	seed := randomize.NewSeed()
+	var err error
+	o := &Management{}
+	if err = randomize.Struct(seed, o, managementDBTypes, true, managementColumnsWithDefault...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	ctx := context.Background()
+	tx := MustTx(boil.BeginTx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if err = o.Insert(ctx, tx, boil.Infer()); err != nil {
+		t.Error(err)
+	}
+
+	count, err := Managements().Count(ctx, tx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 1 {
+		t.Error("want one record, got:", count)
+	}
+
+	if err = randomize.Struct(seed, o, managementDBTypes, true, managementPrimaryKeyColumns...); err != nil {
+		t.Errorf("Unable to randomize Management struct: %s", err)
+	}
+
+	// Remove Primary keys and unique columns from what we plan to update
+	var fields []string
+	if strmangle.StringSliceMatch(managementAllColumns, managementPrimaryKeyColumns) {
+		fields = managementAllColumns
+	} else {
+		fields = strmangle.SetComplement(
+			managementAllColumns,
+			managementPrimaryKeyColumns,
+		)
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(o))
+	typ := reflect.TypeOf(o).Elem()
+	n := typ.NumField()
+
+	updateMap := M{}
+	for _, col := range fields {
+		for i := 0; i < n; i++ {
+			f := typ.Field(i)
+			if f.Tag.Get("boil") == col {
+				updateMap[col] = value.Field(i).Interface()
+			}
+		}
+	}
+
+	slice := ManagementSlice{o}
+	if rowsAff, err := slice.UpdateAll(ctx, tx, updateMap); err != nil {
+		t.Error(err)
+	} else if rowsAff != 1 {
+		t.Error("wanted one record updated but got", rowsAff)
+	}
+}
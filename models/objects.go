@@ -23,83 +23,50 @@ import (
 
 // Object is an object representing the database table.
 type Object struct {
-	ID        null.Int64 `boil:"id" json:"id,omitempty" toml:"id" yaml:"id,omitempty"`
-	Path      string     `boil:"path" json:"path" toml:"path" yaml:"path"`
-	Type      string     `boil:"type" json:"type" toml:"type" yaml:"type"`
-	Size      int64      `boil:"size" json:"size" toml:"size" yaml:"size"`
-	Mtime     time.Time  `boil:"mtime" json:"mtime" toml:"mtime" yaml:"mtime"`
-	Sha256    string     `boil:"sha256" json:"sha256" toml:"sha256" yaml:"sha256"`
-	Status    string     `boil:"status" json:"status" toml:"status" yaml:"status"`
-	CreatedAt time.Time  `boil:"created_at" json:"created_at" toml:"created_at" yaml:"created_at"`
-	UpdatedAt time.Time  `boil:"updated_at" json:"updated_at" toml:"updated_at" yaml:"updated_at"`
+	ID           null.Int64 `boil:"id" json:"id,omitempty" toml:"id" yaml:"id,omitempty"`
+	ManagementID int64      `boil:"management_id" json:"management_id" toml:"management_id" yaml:"management_id"`
+	Path         string     `boil:"path" json:"path" toml:"path" yaml:"path"`
+	Type         string     `boil:"type" json:"type" toml:"type" yaml:"type"`
+	Size         int64      `boil:"size" json:"size" toml:"size" yaml:"size"`
+	Mtime        time.Time  `boil:"mtime" json:"mtime" toml:"mtime" yaml:"mtime"`
+	MtimeNs      int64      `boil:"mtime_ns" json:"mtime_ns" toml:"mtime_ns" yaml:"mtime_ns"`
+	Sha256       string     `boil:"sha256" json:"sha256" toml:"sha256" yaml:"sha256"`
+	Status       string     `boil:"status" json:"status" toml:"status" yaml:"status"`
+	CreatedAt    time.Time  `boil:"created_at" json:"created_at" toml:"created_at" yaml:"created_at"`
+	UpdatedAt    time.Time  `boil:"updated_at" json:"updated_at" toml:"updated_at" yaml:"updated_at"`
 
 	R *objectR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L objectL  `boil:"-" json:"-" toml:"-" yaml:"-"`
 }
 
 var ObjectColumns = struct {
-	ID        string
-	Path      string
-	Type      string
-	Size      string
-	Mtime     string
-	Sha256    string
-	Status    string
-	CreatedAt string
-	UpdatedAt string
+	ID           string
+	ManagementID string
+	Path         string
+	Type         string
+	Size         string
+	Mtime        string
+	MtimeNs      string
+	Sha256       string
+	Status       string
+	CreatedAt    string
+	UpdatedAt    string
 }{
-	ID:        "id",
-	Path:      "path",
-	Type:      "type",
-	Size:      "size",
-	Mtime:     "mtime",
-	Sha256:    "sha256",
-	Status:    "status",
-	CreatedAt: "created_at",
-	UpdatedAt: "updated_at",
+	ID:           "id",
+	ManagementID: "management_id",
+	Path:         "path",
+	Type:         "type",
+	Size:         "size",
+	Mtime:        "mtime",
+	MtimeNs:      "mtime_ns",
+	Sha256:       "sha256",
+	Status:       "status",
+	CreatedAt:    "created_at",
+	UpdatedAt:    "updated_at",
 }
 
 // Generated where
 
-type whereHelpernull_Int64 struct{ field string }
-
-func (w whereHelpernull_Int64) EQ(x null.Int64) qm.QueryMod {
-	return qmhelper.WhereNullEQ(w.field, false, x)
-}
-func (w whereHelpernull_Int64) NEQ(x null.Int64) qm.QueryMod {
-	return qmhelper.WhereNullEQ(w.field, true, x)
-}
-func (w whereHelpernull_Int64) IsNull() qm.QueryMod    { return qmhelper.WhereIsNull(w.field) }
-func (w whereHelpernull_Int64) IsNotNull() qm.QueryMod { return qmhelper.WhereIsNotNull(w.field) }
-func (w whereHelpernull_Int64) LT(x null.Int64) qm.QueryMod {
-	return qmhelper.Where(w.field, qmhelper.LT, x)
-}
-func (w whereHelpernull_Int64) LTE(x null.Int64) qm.QueryMod {
-	return qmhelper.Where(w.field, qmhelper.LTE, x)
-}
-func (w whereHelpernull_Int64) GT(x null.Int64) qm.QueryMod {
-	return qmhelper.Where(w.field, qmhelper.GT, x)
-}
-func (w whereHelpernull_Int64) GTE(x null.Int64) qm.QueryMod {
-	return qmhelper.Where(w.field, qmhelper.GTE, x)
-}
-
-type whereHelperstring struct{ field string }
-
-func (w whereHelperstring) EQ(x string) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.EQ, x) }
-func (w whereHelperstring) NEQ(x string) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.NEQ, x) }
-func (w whereHelperstring) LT(x string) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.LT, x) }
-func (w whereHelperstring) LTE(x string) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.LTE, x) }
-func (w whereHelperstring) GT(x string) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.GT, x) }
-func (w whereHelperstring) GTE(x string) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.GTE, x) }
-func (w whereHelperstring) IN(slice []string) qm.QueryMod {
-	values := make([]interface{}, 0, len(slice))
-	for _, value := range slice {
-		values = append(values, value)
-	}
-	return qm.WhereIn(fmt.Sprintf("%s IN ?", w.field), values...)
-}
-
 type whereHelperint64 struct{ field string }
 
 func (w whereHelperint64) EQ(x int64) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.EQ, x) }
@@ -109,47 +76,30 @@ func (w whereHelperint64) LTE(x int64) qm.QueryMod { return qmhelper.Where(w.fie
 func (w whereHelperint64) GT(x int64) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.GT, x) }
 func (w whereHelperint64) GTE(x int64) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.GTE, x) }
 
-type whereHelpertime_Time struct{ field string }
-
-func (w whereHelpertime_Time) EQ(x time.Time) qm.QueryMod {
-	return qmhelper.Where(w.field, qmhelper.EQ, x)
-}
-func (w whereHelpertime_Time) NEQ(x time.Time) qm.QueryMod {
-	return qmhelper.Where(w.field, qmhelper.NEQ, x)
-}
-func (w whereHelpertime_Time) LT(x time.Time) qm.QueryMod {
-	return qmhelper.Where(w.field, qmhelper.LT, x)
-}
-func (w whereHelpertime_Time) LTE(x time.Time) qm.QueryMod {
-	return qmhelper.Where(w.field, qmhelper.LTE, x)
-}
-func (w whereHelpertime_Time) GT(x time.Time) qm.QueryMod {
-	return qmhelper.Where(w.field, qmhelper.GT, x)
-}
-func (w whereHelpertime_Time) GTE(x time.Time) qm.QueryMod {
-	return qmhelper.Where(w.field, qmhelper.GTE, x)
-}
-
 var ObjectWhere = struct {
-	ID        whereHelpernull_Int64
-	Path      whereHelperstring
-	Type      whereHelperstring
-	Size      whereHelperint64
-	Mtime     whereHelpertime_Time
-	Sha256    whereHelperstring
-	Status    whereHelperstring
-	CreatedAt whereHelpertime_Time
-	UpdatedAt whereHelpertime_Time
+	ID           whereHelpernull_Int64
+	ManagementID whereHelperint64
+	Path         whereHelperstring
+	Type         whereHelperstring
+	Size         whereHelperint64
+	Mtime        whereHelpertime_Time
+	MtimeNs      whereHelperint64
+	Sha256       whereHelperstring
+	Status       whereHelperstring
+	CreatedAt    whereHelpertime_Time
+	UpdatedAt    whereHelpertime_Time
 }{
-	ID:        whereHelpernull_Int64{field: "\"objects\".\"id\""},
-	Path:      whereHelperstring{field: "\"objects\".\"path\""},
-	Type:      whereHelperstring{field: "\"objects\".\"type\""},
-	Size:      whereHelperint64{field: "\"objects\".\"size\""},
-	Mtime:     whereHelpertime_Time{field: "\"objects\".\"mtime\""},
-	Sha256:    whereHelperstring{field: "\"objects\".\"sha256\""},
-	Status:    whereHelperstring{field: "\"objects\".\"status\""},
-	CreatedAt: whereHelpertime_Time{field: "\"objects\".\"created_at\""},
-	UpdatedAt: whereHelpertime_Time{field: "\"objects\".\"updated_at\""},
+	ID:           whereHelpernull_Int64{field: "\"objects\".\"id\""},
+	ManagementID: whereHelperint64{field: "\"objects\".\"management_id\""},
+	Path:         whereHelperstring{field: "\"objects\".\"path\""},
+	Type:         whereHelperstring{field: "\"objects\".\"type\""},
+	Size:         whereHelperint64{field: "\"objects\".\"size\""},
+	Mtime:        whereHelpertime_Time{field: "\"objects\".\"mtime\""},
+	MtimeNs:      whereHelperint64{field: "\"objects\".\"mtime_ns\""},
+	Sha256:       whereHelperstring{field: "\"objects\".\"sha256\""},
+	Status:       whereHelperstring{field: "\"objects\".\"status\""},
+	CreatedAt:    whereHelpertime_Time{field: "\"objects\".\"created_at\""},
+	UpdatedAt:    whereHelpertime_Time{field: "\"objects\".\"updated_at\""},
 }
 
 // ObjectRels is where relationship names are stored.
@@ -169,9 +119,9 @@ func (*objectR) NewStruct() *objectR {
 type objectL struct{}
 
 var (
-	objectAllColumns            = []string{"id", "path", "type", "size", "mtime", "sha256", "status", "created_at", "updated_at"}
+	objectAllColumns            = []string{"id", "management_id", "path", "type", "size", "mtime", "mtime_ns", "sha256", "status", "created_at", "updated_at"}
 	objectColumnsWithoutDefault = []string{"path", "type", "size", "mtime", "sha256", "status", "created_at", "updated_at"}
-	objectColumnsWithDefault    = []string{"id"}
+	objectColumnsWithDefault    = []string{"id", "management_id", "mtime_ns"}
 	objectPrimaryKeyColumns     = []string{"id"}
 )
 
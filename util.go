@@ -18,9 +18,12 @@ func CalcSha256(path string) ([]byte, error) {
 		return nil, err
 	}
 	defer file.Close()
-	r := bufio.NewReader(file)
+	return CalcSha256FromReader(bufio.NewReader(file))
+}
+
+func CalcSha256FromReader(r io.Reader) ([]byte, error) {
 	digest := sha256.New()
-	_, err = io.Copy(digest, r)
+	_, err := io.Copy(digest, r)
 	if err != nil {
 		return nil, err
 	}
@@ -34,3 +37,11 @@ func CalcSha256HexString(path string) (string, error) {
 	}
 	return ToHexString(bs), nil
 }
+
+func CalcSha256HexStringFromReader(r io.Reader) (string, error) {
+	bs, err := CalcSha256FromReader(r)
+	if err != nil {
+		return "", err
+	}
+	return ToHexString(bs), nil
+}